@@ -13,6 +13,9 @@ import (
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
 
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	authorization_store "github.com/SevenTV/ServerGo/src/authorization/store"
 	"github.com/SevenTV/ServerGo/src/configure"
 	"github.com/SevenTV/ServerGo/src/discord"
 	"github.com/SevenTV/ServerGo/src/mongo"
@@ -20,6 +23,7 @@ import (
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
 	_ "github.com/SevenTV/ServerGo/src/redis"
 	"github.com/SevenTV/ServerGo/src/server"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
 	api_websocket "github.com/SevenTV/ServerGo/src/server/api/v2/websocket"
 )
 
@@ -37,6 +41,11 @@ func main() {
 	c := make(chan os.Signal)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
 
+	// Forward audit entries to the configured sinks
+	audit.Setup()
+
+	authorization.SetStore(authorization_store.Database{})
+
 	s := server.New()
 
 	go func() {
@@ -81,6 +90,30 @@ func main() {
 	}
 	log.Infof("Retrieved %s roles", fmt.Sprint(len(roles)))
 
+	// Keep the cached roles up to date
+	WatchRoles(context.Background())
+
+	// Start executing scheduled channel emote changes
+	actions.StartChannelEmoteScheduler(context.Background())
+
+	// Keep global emotes in line with the active global emote sets
+	actions.StartGlobalEmoteSetWorker(context.Background())
+
+	// Keep the channel counts of emotes up to date
+	actions.StartEmoteChannelCountWorker(context.Background())
+
+	// Keep the trending scores of emotes up to date
+	actions.StartEmoteTrendingWorker(context.Background())
+
+	// Set the lowercased name of emotes created before search matched on it
+	actions.StartEmoteNameBackfill(context.Background())
+
+	// Keep the amount of emotes per tag up to date
+	actions.StartEmoteTagWorker(context.Background())
+
+	// Move audit entries past their retention to the archive
+	audit.StartRetentionWorker(context.Background())
+
 	select {}
 }
 
@@ -91,6 +124,9 @@ func Cleanup() {
 		conn.Unregister(context.Background())
 	}
 
+	// Write out the remaining audit entries
+	audit.Close()
+
 	// Logout from discord
 	_ = discord.Discord.CloseWithCode(1000)
 }
@@ -113,6 +149,40 @@ func GetAllRoles(ctx context.Context) ([]datastructure.Role, error) {
 	}
 
 	// Set "AllRoles" value to mongo context
+	cache.RolesMutex.Lock()
 	cache.CachedRoles = roles
+	cache.RolesMutex.Unlock()
 	return roles, nil
 }
+
+// Reload the cached roles whenever a role changes, so that every node sees the change immediately
+func WatchRoles(ctx context.Context) {
+	go func() {
+		for {
+			stream, err := mongo.Database.Collection("roles").Watch(ctx, mongo.Pipeline{})
+			if err == nil {
+				// Roles may have changed while the stream was down
+				if _, err := GetAllRoles(ctx); err != nil {
+					log.Errorf("could not get roles, %s", err)
+				}
+
+				for stream.Next(ctx) {
+					if _, err := GetAllRoles(ctx); err != nil {
+						log.Errorf("could not get roles, %s", err)
+					}
+				}
+				err = stream.Err()
+				_ = stream.Close(ctx)
+			}
+			if err != nil {
+				log.Errorf("mongo change stream, err=%v, col=roles", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}()
+}
@@ -0,0 +1,201 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/SevenTV/ServerGo/src/aws"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The most archive files read by a single search
+const maxArchiveFilesPerSearch = 25
+
+// The search would have to read too many archive files before being able to return any entry
+var ErrArchiveSearchTooWide = fmt.Errorf("too many archive files overlap, narrow the search")
+
+// Search the archived entries, newest first
+// after is the id of the last entry of the previous page, if any
+// One more entry than the limit is returned when available, to tell whether there is a next page
+//
+// When the search stops before reading every archive file which may hold entries, only the entries known to be complete are returned,
+// along with the id to pass as after to resume from the next unread file. The page is then not the last one, even when it isn't full
+func SearchArchive(ctx context.Context, policy *RetentionPolicy, f Filter, after *primitive.ObjectID, limit int64) ([]*datastructure.AuditLog, *primitive.ObjectID, error) {
+	if policy.Archive == ArchiveNDJSON {
+		return searchArchiveFiles(ctx, policy.Bucket, f, after, limit)
+	}
+
+	match := f.Match()
+	if after != nil {
+		match = bson.M{"$and": bson.A{match, bson.M{"_id": bson.M{"$lt": *after}}}}
+	}
+
+	entries := []*datastructure.AuditLog{}
+	cur, err := mongo.Database.Collection("audit_archive").Find(ctx, match, options.Find().SetSort(bson.M{"_id": -1}).SetLimit(limit+1))
+	if err == nil {
+		err = cur.All(ctx, &entries)
+	}
+
+	return entries, nil, err
+}
+
+// Search the archive files overlapping the filter, reading them from newest to oldest
+func searchArchiveFiles(ctx context.Context, bucket string, f Filter, after *primitive.ObjectID, limit int64) ([]*datastructure.AuditLog, *primitive.ObjectID, error) {
+	match := bson.M{}
+	if len(f.Types) > 0 {
+		match["types"] = bson.M{"$in": f.Types}
+	}
+	if f.Since != nil {
+		match["last_id"] = bson.M{"$gte": primitive.NewObjectIDFromTimestamp(*f.Since)}
+	}
+	firstID := bson.M{}
+	if f.Until != nil {
+		firstID["$lt"] = primitive.NewObjectIDFromTimestamp(*f.Until)
+	}
+	if after != nil {
+		if v, ok := firstID["$lt"].(primitive.ObjectID); !ok || idLess(*after, v) {
+			firstID["$lt"] = *after
+		}
+	}
+	if len(firstID) > 0 {
+		match["first_id"] = firstID
+	}
+
+	files := []*datastructure.AuditArchiveFile{}
+	cur, err := mongo.Database.Collection("audit_archive_files").Find(ctx, match, options.Find().SetSort(bson.M{"last_id": -1}))
+	if err == nil {
+		err = cur.All(ctx, &files)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entries := []*datastructure.AuditLog{}
+	for i, file := range files {
+		// Files may overlap, so reading stops only once the next file can't hold a newer entry than the page has
+		if int64(len(entries)) > limit && idLess(file.LastID, entries[limit].ID) {
+			break
+		}
+		if i == maxArchiveFilesPerSearch {
+			// The unread files can only hold entries up to the last id of this one,
+			// so the entries after it are complete and the next page starts with it
+			complete := 0
+			for complete < len(entries) && idLess(file.LastID, entries[complete].ID) {
+				complete++
+			}
+			resume := idAfter(file.LastID)
+			if complete == 0 && after != nil && !idLess(resume, *after) {
+				return nil, nil, ErrArchiveSearchTooWide
+			}
+
+			log.Infof("audit archive, search stopped after reading %d files, resuming from %s", i, file.Key)
+			return entries[:complete], &resume, nil
+		}
+
+		found, err := readArchiveFile(bucket, file.Key, f, after)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, found...)
+
+		sort.Slice(entries, func(a, b int) bool {
+			return idLess(entries[b].ID, entries[a].ID)
+		})
+		if int64(len(entries)) > limit+1 {
+			entries = entries[:limit+1]
+		}
+	}
+
+	return entries, nil, nil
+}
+
+// Read the entries of an archive file which match the filter
+func readArchiveFile(bucket string, key string, f Filter, after *primitive.ObjectID) ([]*datastructure.AuditLog, error) {
+	b, err := aws.DownloadFile(bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	entries := []*datastructure.AuditLog{}
+	scanner := bufio.NewScanner(gz)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		entry := &datastructure.AuditLog{}
+		if err := bson.UnmarshalExtJSON(scanner.Bytes(), true, entry); err != nil {
+			return nil, err
+		}
+		if after != nil && !idLess(entry.ID, *after) {
+			continue
+		}
+		if f.Matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, scanner.Err()
+}
+
+// Whether an entry matches a filter
+// This is the in-memory equivalent of the query from Match
+func (f Filter) Matches(entry *datastructure.AuditLog) bool {
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if t == entry.Type {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.ActorID != nil && entry.CreatedBy != *f.ActorID {
+		return false
+	}
+	if f.TargetType != nil && (entry.Target == nil || entry.Target.Type != *f.TargetType) {
+		return false
+	}
+	if f.TargetID != nil && (entry.Target == nil || entry.Target.ID == nil || *entry.Target.ID != *f.TargetID) {
+		return false
+	}
+	if f.Since != nil && idLess(entry.ID, primitive.NewObjectIDFromTimestamp(*f.Since)) {
+		return false
+	}
+	if f.Until != nil && !idLess(entry.ID, primitive.NewObjectIDFromTimestamp(*f.Until)) {
+		return false
+	}
+
+	return true
+}
+
+func idLess(a primitive.ObjectID, b primitive.ObjectID) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
+
+// Get the id right after another, so that the ids before it include the other
+func idAfter(id primitive.ObjectID) primitive.ObjectID {
+	for i := len(id) - 1; i >= 0; i-- {
+		id[i]++
+		if id[i] != 0 {
+			break
+		}
+	}
+
+	return id
+}
@@ -0,0 +1,312 @@
+package audit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/gofiber/fiber/v2"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// A Sink receives a copy of every audit entry stored in the database
+type Sink interface {
+	Name() string
+	Write(record *Record) error
+	Close() error
+}
+
+// An audit entry in the flat form used by sinks and exports
+type Record struct {
+	ID         string          `json:"id"`
+	Type       int32           `json:"type"`
+	CreatedAt  time.Time       `json:"created_at"`
+	ActorID    string          `json:"actor_id"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Reason     string          `json:"reason"`
+	Changes    []*RecordChange `json:"changes"`
+
+	Request *datastructure.AuditLogRequest `json:"request,omitempty"`
+}
+
+type RecordChange struct {
+	Key      string      `json:"key"`
+	OldValue interface{} `json:"old_value"`
+	NewValue interface{} `json:"new_value"`
+}
+
+// The amount of entries which can wait to be written to each sink
+// Entries are dropped when a sink falls this far behind
+const queueSize = 1024
+
+// How many times writing an entry to a sink is attempted, waiting longer after each failure
+const (
+	maxWriteAttempts = 5
+	minWriteBackoff  = 500 * time.Millisecond
+	maxWriteBackoff  = 30 * time.Second
+)
+
+// A sink along with the entries waiting to be written to it, so that a slow sink doesn't hold back the others
+type sinkQueue struct {
+	dropped uint64 // First, so that it is aligned for atomic operations
+	sink    Sink
+	queue   chan *Record
+	done    chan struct{}
+}
+
+var (
+	queues   []*sinkQueue
+	stopping = make(chan struct{})
+	mtx      sync.RWMutex
+	closed   bool
+)
+
+// Set up the sinks enabled in the config and start forwarding entries to them
+func Setup() {
+	setupProxies()
+
+	sinks := []Sink{}
+	if path := configure.Config.GetString("audit.ndjson.path"); path != "" {
+		s, err := NewNDJSONSink(path, configure.Config.GetInt64("audit.ndjson.max_size"), configure.Config.GetInt("audit.ndjson.max_backups"))
+		if err != nil {
+			log.Errorf("audit, ndjson, err=%v", err)
+		} else {
+			sinks = append(sinks, s)
+		}
+	}
+
+	if configure.Config.GetBool("audit.syslog.enabled") {
+		s, err := NewSyslogSink(configure.Config.GetString("audit.syslog.network"), configure.Config.GetString("audit.syslog.address"), configure.Config.GetString("audit.syslog.tag"))
+		if err != nil {
+			log.Errorf("audit, syslog, err=%v", err)
+		} else {
+			sinks = append(sinks, s)
+		}
+	}
+
+	if url := configure.Config.GetString("audit.http.url"); url != "" {
+		sinks = append(sinks, NewHTTPSink(url, configure.Config.GetString("audit.http.token")))
+	}
+
+	for _, s := range sinks {
+		q := &sinkQueue{
+			sink:  s,
+			queue: make(chan *Record, queueSize),
+			done:  make(chan struct{}),
+		}
+		queues = append(queues, q)
+		go q.run()
+	}
+	if len(queues) > 0 {
+		log.Infof("audit, forwarding entries to %d sinks", len(queues))
+	}
+}
+
+// Flush the pending entries and close all sinks
+// Failed writes are no longer retried while flushing
+func Close() {
+	mtx.Lock()
+	if closed {
+		mtx.Unlock()
+		return
+	}
+	closed = true
+	close(stopping)
+	for _, q := range queues {
+		close(q.queue)
+	}
+	mtx.Unlock()
+
+	for _, q := range queues {
+		<-q.done
+		if err := q.sink.Close(); err != nil {
+			log.WithField("sink", q.sink.Name()).Errorf("audit, err=%v", err)
+		}
+	}
+}
+
+// Get the amount of entries each sink dropped, by name, because it fell too far behind or kept failing to write them
+func Dropped() map[string]uint64 {
+	dropped := make(map[string]uint64, len(queues))
+	for _, q := range queues {
+		dropped[q.sink.Name()] += atomic.LoadUint64(&q.dropped)
+	}
+
+	return dropped
+}
+
+func (q *sinkQueue) run() {
+	defer close(q.done)
+	for r := range q.queue {
+		if err := q.write(r); err != nil {
+			atomic.AddUint64(&q.dropped, 1)
+			log.WithField("sink", q.sink.Name()).Errorf("audit, err=%v, id=%s", err, r.ID)
+		}
+	}
+}
+
+// Write an entry to the sink, retrying with backoff when it fails
+func (q *sinkQueue) write(r *Record) error {
+	backoff := minWriteBackoff
+	for attempt := 1; ; attempt++ {
+		err := q.sink.Write(r)
+		if err == nil || attempt == maxWriteAttempts {
+			return err
+		}
+		log.WithField("sink", q.sink.Name()).Warnf("audit, err=%v, attempt=%d", err, attempt)
+
+		select {
+		case <-time.After(backoff):
+		case <-stopping:
+			return err
+		}
+		if backoff *= 2; backoff > maxWriteBackoff {
+			backoff = maxWriteBackoff
+		}
+	}
+}
+
+// Store an audit entry and forward it to the configured sinks
+// Entries created while handling a request get the request's metadata attached
+func Insert(ctx context.Context, entry *datastructure.AuditLog) error {
+	if entry.ID.IsZero() {
+		entry.ID = primitive.NewObjectID()
+	}
+	if entry.Request == nil {
+		if c, ok := ctx.Value(utils.RequestCtxKey).(*fiber.Ctx); ok {
+			entry.Request = RequestFromFiber(c)
+		}
+	}
+
+	if _, err := mongo.Database.Collection("audit").InsertOne(ctx, entry); err != nil {
+		return err
+	}
+
+	mtx.RLock()
+	defer mtx.RUnlock()
+	if len(queues) > 0 && !closed {
+		r := NewRecord(entry)
+		for _, q := range queues {
+			select {
+			case q.queue <- r:
+			default:
+				atomic.AddUint64(&q.dropped, 1)
+				log.WithField("sink", q.sink.Name()).Errorf("audit, err=sink queue is full, id=%s", entry.ID.Hex())
+			}
+		}
+	}
+
+	return nil
+}
+
+// Turn an audit entry into a Record
+func NewRecord(entry *datastructure.AuditLog) *Record {
+	r := &Record{
+		ID:        entry.ID.Hex(),
+		Type:      entry.Type,
+		CreatedAt: entry.ID.Timestamp(),
+		Changes:   make([]*RecordChange, len(entry.Changes)),
+		Request:   entry.Request,
+	}
+	if !entry.CreatedBy.IsZero() {
+		r.ActorID = entry.CreatedBy.Hex()
+	}
+	if entry.Target != nil {
+		r.TargetType = entry.Target.Type
+		if entry.Target.ID != nil {
+			r.TargetID = entry.Target.ID.Hex()
+		}
+	}
+	if entry.Reason != nil {
+		r.Reason = *entry.Reason
+	}
+	for i, c := range entry.Changes {
+		r.Changes[i] = &RecordChange{
+			Key:      c.Key,
+			OldValue: PlainValue(c.OldValue),
+			NewValue: PlainValue(c.NewValue),
+		}
+	}
+
+	return r
+}
+
+// Turn a change value decoded from the database into plain maps and slices
+// so that it encodes to readable JSON
+func PlainValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case primitive.D:
+		m := make(map[string]interface{}, len(v))
+		for _, e := range v {
+			m[e.Key] = PlainValue(e.Value)
+		}
+		return m
+	case primitive.M:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = PlainValue(e)
+		}
+		return m
+	case primitive.A:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = PlainValue(e)
+		}
+		return a
+	case primitive.ObjectID:
+		return v.Hex()
+	case primitive.DateTime:
+		return v.Time().Format(time.RFC3339)
+	}
+
+	return v
+}
+
+// Criteria for listing audit entries
+type Filter struct {
+	Types      []int32
+	ActorID    *primitive.ObjectID
+	TargetType *string
+	TargetID   *primitive.ObjectID
+	Since      *time.Time
+	Until      *time.Time
+}
+
+// Get the query matching the entries of a filter
+func (f Filter) Match() bson.M {
+	match := bson.M{}
+	if len(f.Types) > 0 {
+		match["type"] = bson.M{"$in": f.Types}
+	}
+	if f.ActorID != nil {
+		match["action_user"] = *f.ActorID
+	}
+	if f.TargetType != nil {
+		match["target.type"] = *f.TargetType
+	}
+	if f.TargetID != nil {
+		match["target.id"] = *f.TargetID
+	}
+
+	// Entries carry no separate timestamp, the time range is applied to their ids
+	idRange := bson.M{}
+	if f.Since != nil {
+		idRange["$gte"] = primitive.NewObjectIDFromTimestamp(*f.Since)
+	}
+	if f.Until != nil {
+		idRange["$lt"] = primitive.NewObjectIDFromTimestamp(*f.Until)
+	}
+	if len(idRange) > 0 {
+		match["_id"] = idRange
+	}
+
+	return match
+}
@@ -0,0 +1,129 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+// Writes audit records to a file, one JSON document per line
+// The file is rotated once it grows past its maximum size
+type NDJSONSink struct {
+	mtx        sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+
+	file *os.File
+	size int64
+}
+
+// Create a sink writing to the file at path
+// maxSize is in bytes and defaults to 100MB, maxBackups is the amount of rotated files to keep, or all of them if 0
+func NewNDJSONSink(path string, maxSize int64, maxBackups int) (*NDJSONSink, error) {
+	if maxSize <= 0 {
+		maxSize = 100 * 1024 * 1024
+	}
+
+	s := &NDJSONSink{
+		path:       path,
+		maxSize:    maxSize,
+		maxBackups: maxBackups,
+	}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *NDJSONSink) Name() string {
+	return "ndjson"
+}
+
+func (s *NDJSONSink) Write(record *Record) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.size > 0 && s.size+int64(len(b)) > s.maxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.file.Write(b)
+	s.size += int64(n)
+	return err
+}
+
+func (s *NDJSONSink) Close() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	return s.file.Close()
+}
+
+func (s *NDJSONSink) open() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	s.file = f
+	s.size = info.Size()
+	return nil
+}
+
+// Move the current file aside with the time of rotation in its name and start a new one
+func (s *NDJSONSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+
+	backup := fmt.Sprintf("%s.%s", s.path, time.Now().UTC().Format("20060102T150405.000"))
+	if err := os.Rename(s.path, backup); err != nil {
+		return err
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	if s.maxBackups > 0 {
+		backups, err := filepath.Glob(s.path + ".*")
+		if err != nil {
+			return err
+		}
+		// The timestamp suffix makes the names sort from oldest to newest
+		sort.Strings(backups)
+		for len(backups) > s.maxBackups {
+			if err := os.Remove(backups[0]); err != nil {
+				return err
+			}
+			backups = backups[1:]
+		}
+	}
+
+	return nil
+}
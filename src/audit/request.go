@@ -0,0 +1,90 @@
+package audit
+
+import (
+	"net"
+	"strings"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// The proxies trusted to set the address of clients in proxyHeader
+	trustedProxies []*net.IPNet
+	proxyHeader    string
+)
+
+// Read which proxies are trusted to forward the address of clients
+// The fiber version in use can't check proxies itself, and c.IP() returns the configured header of anyone
+func setupProxies() {
+	proxyHeader = configure.Config.GetString("audit.proxy_header")
+	for _, s := range configure.Config.GetStringSlice("audit.trusted_proxies") {
+		if !strings.Contains(s, "/") {
+			if ip := net.ParseIP(s); ip != nil && ip.To4() != nil {
+				s += "/32"
+			} else {
+				s += "/128"
+			}
+		}
+
+		_, n, err := net.ParseCIDR(s)
+		if err != nil {
+			log.Errorf("audit, trusted proxy, err=%v", err)
+			continue
+		}
+		trustedProxies = append(trustedProxies, n)
+	}
+}
+
+func isTrustedProxy(ip net.IP) bool {
+	for _, n := range trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get the address of the client of a request, and whether it was forwarded by a trusted proxy
+// Proxies append the address they received the request from, so the client is the last address which isn't a trusted proxy
+func clientIP(c *fiber.Ctx) (net.IP, bool) {
+	ip := c.Context().RemoteIP()
+	if proxyHeader == "" || !isTrustedProxy(ip) {
+		return ip, false
+	}
+
+	forwarded := false
+	addrs := strings.Split(c.Get(proxyHeader), ",")
+	for i := len(addrs) - 1; i >= 0; i-- {
+		addr := net.ParseIP(strings.TrimSpace(addrs[i]))
+		if addr == nil {
+			break
+		}
+
+		ip, forwarded = addr, true
+		if !isTrustedProxy(addr) {
+			break
+		}
+	}
+
+	return ip, forwarded
+}
+
+// Get the metadata of a request to attach to the audit entries created while handling it
+// The values are copied, as fiber reuses its buffers once the request is done
+func RequestFromFiber(c *fiber.Ctx) *datastructure.AuditLogRequest {
+	ip, forwarded := clientIP(c)
+	requestID, _ := c.Locals("requestid").(string)
+
+	return &datastructure.AuditLogRequest{
+		IP:        ip.String(),
+		Forwarded: forwarded,
+		UserAgent: utils.CopyString(c.Get(fiber.HeaderUserAgent)),
+		RequestID: utils.CopyString(requestID),
+		Node:      configure.NodeName,
+	}
+}
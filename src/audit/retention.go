@@ -0,0 +1,246 @@
+package audit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/aws"
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/bsm/redislock"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Where entries past their retention are moved to
+const (
+	ArchiveCollection = "collection" // The audit_archive collection
+	ArchiveNDJSON     = "ndjson"     // Compressed NDJSON files in storage
+)
+
+// The amount of entries moved at once, and the most entries per archive file
+const archiveBatchSize = 1000
+
+// How often the retention policy is applied
+const retentionInterval = time.Hour
+
+// The retention policy from the config
+type RetentionPolicy struct {
+	Types   map[int32]time.Duration // How long entries of a type are kept
+	Default time.Duration           // How long entries of other types are kept, 0 keeps them forever
+	Archive string
+	Bucket  string
+}
+
+// Read the retention policy from the config
+func GetRetentionPolicy() (*RetentionPolicy, error) {
+	p := &RetentionPolicy{
+		Types:   map[int32]time.Duration{},
+		Archive: configure.Config.GetString("audit.retention.archive"),
+		Bucket:  configure.Config.GetString("audit.retention.bucket"),
+	}
+	if p.Archive == "" {
+		p.Archive = ArchiveCollection
+	}
+	if p.Archive != ArchiveCollection && p.Archive != ArchiveNDJSON {
+		return nil, fmt.Errorf("unknown archive %q", p.Archive)
+	}
+	if p.Archive == ArchiveNDJSON && p.Bucket == "" {
+		return nil, fmt.Errorf("a bucket is required to archive to ndjson")
+	}
+
+	if s := configure.Config.GetString("audit.retention.default"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default retention, %v", err)
+		}
+		p.Default = d
+	}
+
+	for k, v := range configure.Config.GetStringMapString("audit.retention.types") {
+		t, err := strconv.Atoi(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit log type %q", k)
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid retention for type %d, %v", t, err)
+		}
+		p.Types[int32(t)] = d
+	}
+
+	return p, nil
+}
+
+// Periodically move audit entries past their retention to the archive
+// Only one node applies the policy at a time
+func StartRetentionWorker(ctx context.Context) {
+	policy, err := GetRetentionPolicy()
+	if err != nil {
+		log.Errorf("audit retention, err=%v", err)
+		return
+	}
+	if policy.Default == 0 && len(policy.Types) == 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(retentionInterval)
+		defer ticker.Stop()
+
+		for {
+			lock, err := redis.GetLocker().Obtain(ctx, "lock:audit-retention", retentionInterval, nil)
+			if err == nil {
+				if err := ApplyRetention(ctx, policy); err != nil {
+					log.Errorf("audit retention, err=%v", err)
+				}
+				_ = lock.Release(ctx)
+			} else if err != redislock.ErrNotObtained {
+				log.Errorf("redis, err=%v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Move all entries past their retention to the archive
+func ApplyRetention(ctx context.Context, policy *RetentionPolicy) error {
+	listed := make([]int32, 0, len(policy.Types))
+	for t, d := range policy.Types {
+		listed = append(listed, t)
+		if d <= 0 {
+			continue
+		}
+		if err := archiveEntries(ctx, policy, bson.M{
+			"type": t,
+			"_id":  bson.M{"$lt": primitive.NewObjectIDFromTimestamp(time.Now().Add(-d))},
+		}); err != nil {
+			return err
+		}
+	}
+
+	if policy.Default > 0 {
+		return archiveEntries(ctx, policy, bson.M{
+			"type": bson.M{"$nin": listed},
+			"_id":  bson.M{"$lt": primitive.NewObjectIDFromTimestamp(time.Now().Add(-policy.Default))},
+		})
+	}
+
+	return nil
+}
+
+// Move the entries matching a query to the archive, oldest first
+func archiveEntries(ctx context.Context, policy *RetentionPolicy, match bson.M) error {
+	moved := 0
+	for {
+		entries := []*datastructure.AuditLog{}
+		cur, err := mongo.Database.Collection("audit").Find(ctx, match, options.Find().SetSort(bson.M{"_id": 1}).SetLimit(archiveBatchSize))
+		if err == nil {
+			err = cur.All(ctx, &entries)
+		}
+		if err != nil {
+			return err
+		}
+		if len(entries) == 0 {
+			break
+		}
+
+		switch policy.Archive {
+		case ArchiveCollection:
+			err = archiveToCollection(ctx, entries)
+		case ArchiveNDJSON:
+			err = archiveToNDJSON(ctx, policy.Bucket, entries)
+		}
+		if err != nil {
+			return err
+		}
+
+		// Entries are only removed once they are safely archived
+		ids := make([]primitive.ObjectID, len(entries))
+		for i, e := range entries {
+			ids[i] = e.ID
+		}
+		if _, err := mongo.Database.Collection("audit").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+			return err
+		}
+
+		moved += len(entries)
+		if len(entries) < archiveBatchSize {
+			break
+		}
+	}
+
+	if moved > 0 {
+		log.Infof("audit retention, archived %d entries", moved)
+	}
+	return nil
+}
+
+func archiveToCollection(ctx context.Context, entries []*datastructure.AuditLog) error {
+	// Replacing makes archiving a batch again harmless, should a node fail before removing it
+	models := make([]mongo.WriteModel, len(entries))
+	for i, e := range entries {
+		models[i] = mongo.NewReplaceOneModel().SetFilter(bson.M{"_id": e.ID}).SetReplacement(e).SetUpsert(true)
+	}
+
+	_, err := mongo.Database.Collection("audit_archive").BulkWrite(ctx, models)
+	return err
+}
+
+func archiveToNDJSON(ctx context.Context, bucket string, entries []*datastructure.AuditLog) error {
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	types := []int32{}
+	seen := map[int32]bool{}
+	for _, e := range entries {
+		// Extended JSON keeps the types of the stored values
+		b, err := bson.MarshalExtJSON(e, true, false)
+		if err != nil {
+			return err
+		}
+		if _, err := gz.Write(append(b, '\n')); err != nil {
+			return err
+		}
+
+		if !seen[e.Type] {
+			seen[e.Type] = true
+			types = append(types, e.Type)
+		}
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	first := entries[0].ID
+	last := entries[len(entries)-1].ID
+	file := &datastructure.AuditArchiveFile{
+		ID:        primitive.NewObjectID(),
+		Key:       fmt.Sprintf("audit/%s/%s-%s.ndjson.gz", first.Timestamp().UTC().Format("2006-01"), first.Hex(), last.Hex()),
+		Types:     types,
+		FirstID:   first,
+		LastID:    last,
+		Count:     int32(len(entries)),
+		CreatedAt: time.Now(),
+	}
+
+	contentType := "application/gzip"
+	if err := aws.UploadPrivateFile(bucket, file.Key, buf.Bytes(), &contentType); err != nil {
+		return err
+	}
+
+	_, err := mongo.Database.Collection("audit_archive_files").InsertOne(ctx, file)
+	return err
+}
@@ -0,0 +1,42 @@
+package audit
+
+import (
+	"log/syslog"
+)
+
+// Writes audit records as JSON messages to a syslog daemon
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// Create a sink writing to syslog
+// An empty network and address use the local syslog daemon
+func NewSyslogSink(network, address, tag string) (*SyslogSink, error) {
+	if tag == "" {
+		tag = "seventv-audit"
+	}
+
+	w, err := syslog.Dial(network, address, syslog.LOG_INFO|syslog.LOG_AUTH, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SyslogSink{w: w}, nil
+}
+
+func (s *SyslogSink) Name() string {
+	return "syslog"
+}
+
+func (s *SyslogSink) Write(record *Record) error {
+	b, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	return s.w.Info(string(b))
+}
+
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
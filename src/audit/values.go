@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Read a list of ids stored as the value of a change
+// Changes come back from the database as generic arrays, and empty lists may have been stored as null
+func ObjectIDs(v interface{}) ([]primitive.ObjectID, bool) {
+	if v == nil {
+		return []primitive.ObjectID{}, true
+	}
+	a, ok := v.(primitive.A)
+	if !ok {
+		return nil, false
+	}
+
+	ids := make([]primitive.ObjectID, len(a))
+	for i, e := range a {
+		if ids[i], ok = e.(primitive.ObjectID); !ok {
+			return nil, false
+		}
+	}
+
+	return ids, true
+}
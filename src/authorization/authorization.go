@@ -0,0 +1,306 @@
+package authorization
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var (
+	ErrAccessDenied = fmt.Errorf("Insufficient Privilege")
+	ErrBanned       = fmt.Errorf("User Is Banned")
+	ErrInternal     = fmt.Errorf("Internal Server Error")
+)
+
+// Where the state decisions depend on is looked up
+// Kept apart from the decisions themselves so that they can be made without a database
+type Store interface {
+	// Whether a user is banned
+	IsBanned(ctx context.Context, userID primitive.ObjectID) (bool, error)
+	// The permissions of a user as an editor of a channel, which are 0 if they aren't an editor
+	GetEditorPermissions(ctx context.Context, channelID primitive.ObjectID, userID primitive.ObjectID) (int64, error)
+}
+
+var store Store
+
+// Set where authorization looks up state, which must be done on startup
+// Errors returned by the store should be ErrInternal, after logging what went wrong
+func SetStore(s Store) {
+	store = s
+}
+
+// Something an actor may be allowed to do
+type Action int
+
+const (
+	// Create emotes. There is no resource
+	EmoteCreate Action = iota + 1
+	// Edit, delete or restore an emote, or request it to become global. The resource is the emote
+	// Allowed to its owner, the editors of its owner able to edit emotes and users able to edit all emotes
+	EmoteEdit
+	// See the details of an emote only meant for its owner, such as why it was disabled. The resource is the emote
+	EmoteDetailsView
+	// Moderate emotes: see hidden emotes, change their global state, disable them and manage tags and global emote sets
+	// There is no resource
+	EmoteModerate
+	// Manage the emotes of a channel, such as seeing its scheduled changes. The resource is the channel
+	// Allowed to its owner, its editors able to add or remove emotes and users able to manage other users
+	// Banned channels can't be managed, which goes for all channel actions below
+	ChannelEdit
+	// Add emotes to a channel. The resource is the channel
+	ChannelEmotesAdd
+	// Remove emotes from a channel. The resource is the channel
+	ChannelEmotesRemove
+	// Replace the emotes of a channel, which both adds and removes emotes. The resource is the channel
+	ChannelEmotesSet
+	// Upload emotes on behalf of a channel. The resource is the channel
+	ChannelEmoteUpload
+	// Add and remove the editors of a channel, or change their permissions. The resource is the channel
+	// Allowed to its owner, its editors able to manage editors and users able to manage other users
+	ChannelEditorsManage
+	// See the activity of a channel. The resource is the channel
+	// Allowed to its owner, its editors and users able to browse the audit log
+	ChannelActivityView
+	// See the private details of a user, such as their email. The resource is the user
+	UserPrivateView
+	// Change one's own account, such as favorites, notifications and editor invitations. There is no resource
+	AccountEdit
+	// Manage another user, such as granting them emote slots. The resource is the user, whose role must be below the actor's
+	UserManage
+	// Ban or unban a user. The resource is the user, whose role must be below the actor's
+	UserBan
+	// See the bans of users. There is no resource
+	BansView
+	// Create, edit or delete a role. The resource is the role, which must be below the actor's
+	RoleManage
+	// See reports. There is no resource
+	ReportsView
+	// Browse the audit log. There is no resource
+	AuditLogsView
+	// Administrate the site, such as exporting the audit log. There is no resource
+	Administrate
+)
+
+// Verify that an actor may perform an action on a resource
+// The error is ErrAccessDenied or ErrBanned if they may not, or ErrInternal if this could not be verified
+func Authorize(ctx context.Context, actor *datastructure.User, action Action, resource interface{}) error {
+	if actor == nil {
+		return ErrAccessDenied
+	}
+
+	// Access tokens are limited to their role permissions, which HasPermission honours,
+	// and to their scopes for what is allowed by owning or editing something
+	if scope, ok := accessTokenScopes[action]; ok && actor.AccessToken != nil && !utils.BitField.HasBits(actor.AccessToken.Scopes, scope) {
+		return ErrAccessDenied
+	}
+
+	switch action {
+	case EmoteCreate:
+		return require(actor.HasPermission(datastructure.RolePermissionEmoteCreate))
+
+	case EmoteEdit:
+		emote, ok := resource.(*datastructure.Emote)
+		if !ok {
+			return invalidResource(action, resource)
+		}
+		if actor.HasPermission(datastructure.RolePermissionEmoteEditAll) || emote.OwnerID == actor.ID {
+			return nil
+		}
+
+		permissions, err := GetEditorPermissions(ctx, emote.OwnerID, actor.ID)
+		if err != nil {
+			return err
+		}
+		return require(utils.BitField.HasBits(permissions, datastructure.EditorPermissionEditEmotes))
+
+	case EmoteDetailsView:
+		emote, ok := resource.(*datastructure.Emote)
+		if !ok {
+			return invalidResource(action, resource)
+		}
+		return require(emote.OwnerID == actor.ID || actor.HasPermission(datastructure.RolePermissionEmoteEditAll))
+
+	case EmoteModerate:
+		return require(actor.HasPermission(datastructure.RolePermissionEmoteEditAll))
+
+	case ChannelEdit, ChannelEmotesAdd, ChannelEmotesRemove, ChannelEmotesSet, ChannelEmoteUpload, ChannelEditorsManage:
+		channel, ok := resource.(*datastructure.User)
+		if !ok {
+			return invalidResource(action, resource)
+		}
+
+		banned, err := IsBanned(ctx, channel.ID)
+		if err != nil {
+			return err
+		}
+		if banned {
+			return ErrBanned
+		}
+
+		if actor.HasPermission(datastructure.RolePermissionManageUsers) {
+			return nil
+		}
+		if channel.ID == actor.ID {
+			return require(action != ChannelEditorsManage || actor.HasPermission(datastructure.RolePermissionManageEditors))
+		}
+
+		permissions, ok := channel.GetEditorPermissions(actor.ID)
+		if !ok {
+			return ErrAccessDenied
+		}
+		if action == ChannelEdit {
+			return require(permissions&(datastructure.EditorPermissionAddEmotes|datastructure.EditorPermissionRemoveEmotes) != 0)
+		}
+		return require(utils.BitField.HasBits(permissions, editorPermissions[action]))
+
+	case ChannelActivityView:
+		channel, ok := resource.(*datastructure.User)
+		if !ok {
+			return invalidResource(action, resource)
+		}
+		_, editor := channel.GetEditorPermissions(actor.ID)
+		return require(channel.ID == actor.ID || editor || canBrowseAuditLogs(actor))
+
+	case UserPrivateView:
+		user, ok := resource.(*datastructure.User)
+		if !ok {
+			return invalidResource(action, resource)
+		}
+		return require(user.ID == actor.ID || actor.HasPermission(datastructure.RolePermissionManageUsers))
+
+	case AccountEdit:
+		return nil
+
+	case UserManage, UserBan:
+		user, ok := resource.(*datastructure.User)
+		if !ok {
+			return invalidResource(action, resource)
+		}
+
+		flag := datastructure.RolePermissionManageUsers
+		if action == UserBan {
+			flag = datastructure.RolePermissionBanUsers
+		}
+		return require(actor.HasPermission(flag) && IsAbove(actor, userRole(user)))
+
+	case BansView:
+		return require(actor.HasPermission(datastructure.RolePermissionBanUsers))
+
+	case RoleManage:
+		role, ok := resource.(*datastructure.Role)
+		if !ok {
+			return invalidResource(action, resource)
+		}
+		return require(actor.HasPermission(datastructure.RolePermissionManageRoles) && IsAbove(actor, role))
+
+	case ReportsView:
+		return require(actor.HasPermission(datastructure.RolePermissionManageReports))
+
+	case AuditLogsView:
+		return require(canBrowseAuditLogs(actor))
+
+	case Administrate:
+		return require(actor.HasPermission(datastructure.RolePermissionAdministrator))
+	}
+
+	log.Errorf("authorization, err=unknown action %d", action)
+	return ErrInternal
+}
+
+// Whether an actor may perform an action on a resource
+// Failures to verify it are logged and treated as a refusal
+func Can(ctx context.Context, actor *datastructure.User, action Action, resource interface{}) bool {
+	return Authorize(ctx, actor, action, resource) == nil
+}
+
+// Whether the role of an actor is above a role
+func IsAbove(actor *datastructure.User, role *datastructure.Role) bool {
+	return actor.Role != nil && role.Position < actor.Role.Position
+}
+
+// Whether an actor may grant a set of permissions to others, which requires holding them
+func CanGrant(actor *datastructure.User, permissions int64) bool {
+	if actor.HasPermission(datastructure.RolePermissionAdministrator) {
+		return true
+	}
+
+	return utils.BitField.RemoveBits(permissions, actor.GetPermissions()) == 0
+}
+
+// Whether an actor may grant or take away a set of editor permissions in a channel
+// Editors may only do so with permissions they hold themselves, unlike the channel's owner and users able to manage other users
+func CanGrantEditor(actor *datastructure.User, channel *datastructure.User, permissions int64) bool {
+	if channel.ID == actor.ID || actor.HasPermission(datastructure.RolePermissionManageUsers) {
+		return true
+	}
+
+	held, ok := channel.GetEditorPermissions(actor.ID)
+	return ok && utils.BitField.RemoveBits(permissions, held) == 0
+}
+
+// Whether a user is banned
+func IsBanned(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	return store.IsBanned(ctx, userID)
+}
+
+// Get the permissions of a user as an editor of a channel, which are 0 if they aren't an editor
+func GetEditorPermissions(ctx context.Context, channelID primitive.ObjectID, userID primitive.ObjectID) (int64, error) {
+	return store.GetEditorPermissions(ctx, channelID, userID)
+}
+
+// The editor permissions needed for the channel actions
+var editorPermissions = map[Action]int64{
+	ChannelEmotesAdd:     datastructure.EditorPermissionAddEmotes,
+	ChannelEmotesRemove:  datastructure.EditorPermissionRemoveEmotes,
+	ChannelEmotesSet:     datastructure.EditorPermissionAddEmotes | datastructure.EditorPermissionRemoveEmotes,
+	ChannelEmoteUpload:   datastructure.EditorPermissionUploadEmotes,
+	ChannelEditorsManage: datastructure.EditorPermissionManageEditors,
+}
+
+// The access token scopes needed for the actions allowed by owning or editing something
+var accessTokenScopes = map[Action]int64{
+	EmoteEdit:            datastructure.AccessTokenScopeEmotes,
+	EmoteDetailsView:     datastructure.AccessTokenScopeEmotes,
+	ChannelEdit:          datastructure.AccessTokenScopeChannelEmotes,
+	ChannelEmotesAdd:     datastructure.AccessTokenScopeChannelEmotes,
+	ChannelEmotesRemove:  datastructure.AccessTokenScopeChannelEmotes,
+	ChannelEmotesSet:     datastructure.AccessTokenScopeChannelEmotes,
+	ChannelEmoteUpload:   datastructure.AccessTokenScopeChannelEmotes,
+	ChannelEditorsManage: datastructure.AccessTokenScopeChannelEditors,
+	ChannelActivityView:  datastructure.AccessTokenScopePrivateRead,
+	UserPrivateView:      datastructure.AccessTokenScopePrivateRead,
+	AccountEdit:          datastructure.AccessTokenScopeAccount,
+}
+
+func canBrowseAuditLogs(actor *datastructure.User) bool {
+	return actor.HasPermission(datastructure.RolePermissionManageUsers) ||
+		actor.HasPermission(datastructure.RolePermissionEmoteEditAll) ||
+		actor.HasPermission(datastructure.RolePermissionManageReports)
+}
+
+// Get the role of a user, which isn't set on users read from the database
+func userRole(user *datastructure.User) *datastructure.Role {
+	if user.Role != nil {
+		return user.Role
+	}
+
+	role := datastructure.GetRole(context.Background(), user.RoleID)
+	return &role
+}
+
+func require(allowed bool) error {
+	if !allowed {
+		return ErrAccessDenied
+	}
+
+	return nil
+}
+
+func invalidResource(action Action, resource interface{}) error {
+	log.Errorf("authorization, err=invalid resource %T for action %d", resource, action)
+	return ErrInternal
+}
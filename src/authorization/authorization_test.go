@@ -0,0 +1,396 @@
+package authorization
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type editorKey struct {
+	channelID primitive.ObjectID
+	userID    primitive.ObjectID
+}
+
+// A store answering from memory
+type fakeStore struct {
+	banned  map[primitive.ObjectID]bool
+	editors map[editorKey]int64
+	err     error
+}
+
+func (s *fakeStore) IsBanned(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	return s.banned[userID], s.err
+}
+
+func (s *fakeStore) GetEditorPermissions(ctx context.Context, channelID primitive.ObjectID, userID primitive.ObjectID) (int64, error) {
+	return s.editors[editorKey{channelID, userID}], s.err
+}
+
+func newUser(position int32, allowed int64) *datastructure.User {
+	return &datastructure.User{
+		ID:   primitive.NewObjectID(),
+		Role: &datastructure.Role{ID: primitive.NewObjectID(), Position: position, Allowed: allowed},
+	}
+}
+
+// A channel with an editor holding some permissions
+func newChannel(editor *datastructure.User, permissions int64) *datastructure.User {
+	channel := newUser(0, datastructure.RolePermissionDefault)
+	channel.EditorIDs = []primitive.ObjectID{editor.ID}
+	channel.EditorPermissions = map[string]int64{editor.ID.Hex(): permissions}
+	return channel
+}
+
+func TestAuthorize(t *testing.T) {
+	owner := newUser(0, datastructure.RolePermissionDefault)
+	ownerWithoutEditors := newUser(0, datastructure.RolePermissionEmoteCreate)
+	stranger := newUser(0, datastructure.RolePermissionDefault)
+	editor := newUser(0, datastructure.RolePermissionDefault)
+	moderator := newUser(10, datastructure.RolePermissionEmoteEditAll|datastructure.RolePermissionManageUsers|datastructure.RolePermissionBanUsers)
+	admin := newUser(20, datastructure.RolePermissionAdministrator)
+
+	emote := &datastructure.Emote{ID: primitive.NewObjectID(), OwnerID: owner.ID}
+	ownerChannel := owner
+	bannedChannel := newChannel(editor, datastructure.EditorPermissionAll)
+	lowRole := &datastructure.Role{Position: 5}
+	highRole := &datastructure.Role{Position: 15}
+	equalUser := newUser(10, 0)
+
+	store := &fakeStore{
+		banned: map[primitive.ObjectID]bool{bannedChannel.ID: true},
+		editors: map[editorKey]int64{
+			{owner.ID, editor.ID}: datastructure.EditorPermissionEditEmotes,
+		},
+	}
+	SetStore(store)
+
+	tests := []struct {
+		name     string
+		actor    *datastructure.User
+		action   Action
+		resource interface{}
+		want     error
+	}{
+		{"nil actor", nil, EmoteCreate, nil, ErrAccessDenied},
+		{"nil actor on channel", nil, ChannelEmotesAdd, ownerChannel, ErrAccessDenied},
+		{"unknown action", owner, Action(0), nil, ErrInternal},
+		{"wrong resource for emote", owner, EmoteEdit, owner, ErrInternal},
+		{"wrong resource for channel", owner, ChannelEmotesAdd, emote, ErrInternal},
+		{"wrong resource for role", admin, RoleManage, owner, ErrInternal},
+
+		{"create emote", owner, EmoteCreate, nil, nil},
+		{"create emote without permission", newUser(0, 0), EmoteCreate, nil, ErrAccessDenied},
+
+		{"owner edits emote", owner, EmoteEdit, emote, nil},
+		{"stranger edits emote", stranger, EmoteEdit, emote, ErrAccessDenied},
+		{"editor able to edit emotes edits emote", editor, EmoteEdit, emote, nil},
+		{"moderator edits emote", moderator, EmoteEdit, emote, nil},
+		{"owner views emote details", owner, EmoteDetailsView, emote, nil},
+		{"stranger views emote details", stranger, EmoteDetailsView, emote, ErrAccessDenied},
+		{"stranger moderates emotes", stranger, EmoteModerate, nil, ErrAccessDenied},
+		{"moderator moderates emotes", moderator, EmoteModerate, nil, nil},
+
+		{"owner adds channel emotes", owner, ChannelEmotesAdd, ownerChannel, nil},
+		{"owner sets channel emotes", owner, ChannelEmotesSet, ownerChannel, nil},
+		{"owner uploads channel emotes", owner, ChannelEmoteUpload, ownerChannel, nil},
+		{"owner manages editors", owner, ChannelEditorsManage, ownerChannel, nil},
+		{"owner without manage editors manages editors", ownerWithoutEditors, ChannelEditorsManage, ownerWithoutEditors, ErrAccessDenied},
+		{"stranger adds channel emotes", stranger, ChannelEmotesAdd, ownerChannel, ErrAccessDenied},
+		{"stranger edits channel", stranger, ChannelEdit, ownerChannel, ErrAccessDenied},
+		{"moderator adds channel emotes", moderator, ChannelEmotesAdd, ownerChannel, nil},
+		{"editor of banned channel", editor, ChannelEmotesAdd, bannedChannel, ErrBanned},
+		{"owner of banned channel", bannedChannel, ChannelEmotesAdd, bannedChannel, ErrBanned},
+		{"moderator on banned channel", moderator, ChannelEmotesAdd, bannedChannel, ErrBanned},
+
+		{"owner views activity", owner, ChannelActivityView, ownerChannel, nil},
+		{"editor views activity", editor, ChannelActivityView, newChannel(editor, 0), nil},
+		{"stranger views activity", stranger, ChannelActivityView, ownerChannel, ErrAccessDenied},
+		{"moderator views activity", moderator, ChannelActivityView, ownerChannel, nil},
+
+		{"user views own private details", owner, UserPrivateView, owner, nil},
+		{"stranger views private details", stranger, UserPrivateView, owner, ErrAccessDenied},
+		{"moderator views private details", moderator, UserPrivateView, owner, nil},
+
+		{"moderator manages user below", moderator, UserManage, owner, nil},
+		{"moderator manages user of equal position", moderator, UserManage, equalUser, ErrAccessDenied},
+		{"moderator manages admin", moderator, UserManage, admin, ErrAccessDenied},
+		{"moderator bans user below", moderator, UserBan, owner, nil},
+		{"user bans user", stranger, UserBan, owner, ErrAccessDenied},
+		{"moderator views bans", moderator, BansView, nil, nil},
+		{"user views bans", stranger, BansView, nil, ErrAccessDenied},
+
+		{"admin manages role below", admin, RoleManage, lowRole, nil},
+		{"admin manages role above", admin, RoleManage, &datastructure.Role{Position: 30}, ErrAccessDenied},
+		{"moderator manages role", moderator, RoleManage, lowRole, ErrAccessDenied},
+		{"admin manages role of equal position", admin, RoleManage, &datastructure.Role{Position: 20}, ErrAccessDenied},
+		{"admin manages role just below", admin, RoleManage, highRole, nil},
+
+		{"moderator views audit logs", moderator, AuditLogsView, nil, nil},
+		{"user views audit logs", stranger, AuditLogsView, nil, ErrAccessDenied},
+		{"admin administrates", admin, Administrate, nil, nil},
+		{"moderator administrates", moderator, Administrate, nil, ErrAccessDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Authorize(context.Background(), tt.actor, tt.action, tt.resource); got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeEditorPermissions(t *testing.T) {
+	SetStore(&fakeStore{})
+
+	actions := []struct {
+		action Action
+		needs  int64
+	}{
+		{ChannelEmotesAdd, datastructure.EditorPermissionAddEmotes},
+		{ChannelEmotesRemove, datastructure.EditorPermissionRemoveEmotes},
+		{ChannelEmotesSet, datastructure.EditorPermissionAddEmotes | datastructure.EditorPermissionRemoveEmotes},
+		{ChannelEmoteUpload, datastructure.EditorPermissionUploadEmotes},
+		{ChannelEditorsManage, datastructure.EditorPermissionManageEditors},
+	}
+	bits := []int64{
+		datastructure.EditorPermissionAddEmotes,
+		datastructure.EditorPermissionRemoveEmotes,
+		datastructure.EditorPermissionUploadEmotes,
+		datastructure.EditorPermissionEditEmotes,
+		datastructure.EditorPermissionManageEditors,
+	}
+
+	for _, a := range actions {
+		for _, bit := range bits {
+			// The editor holds either only this bit, or every bit but this one
+			for _, permissions := range []int64{bit, datastructure.EditorPermissionAll &^ bit} {
+				editor := newUser(0, datastructure.RolePermissionDefault)
+				channel := newChannel(editor, permissions)
+
+				var want error
+				if permissions&a.needs != a.needs {
+					want = ErrAccessDenied
+				}
+				if got := Authorize(context.Background(), editor, a.action, channel); got != want {
+					t.Errorf("action %d with editor permissions %d: Authorize() = %v, want %v", a.action, permissions, got, want)
+				}
+			}
+		}
+	}
+
+	// Editing the channel needs either adding or removing emotes
+	for _, bit := range bits {
+		editor := newUser(0, datastructure.RolePermissionDefault)
+		channel := newChannel(editor, bit)
+
+		var want error
+		if bit != datastructure.EditorPermissionAddEmotes && bit != datastructure.EditorPermissionRemoveEmotes {
+			want = ErrAccessDenied
+		}
+		if got := Authorize(context.Background(), editor, ChannelEdit, channel); got != want {
+			t.Errorf("ChannelEdit with editor permissions %d: Authorize() = %v, want %v", bit, got, want)
+		}
+	}
+
+	// Editors without a stored bitset hold the default permissions
+	editor := newUser(0, datastructure.RolePermissionDefault)
+	channel := newUser(0, datastructure.RolePermissionDefault)
+	channel.EditorIDs = []primitive.ObjectID{editor.ID}
+	if err := Authorize(context.Background(), editor, ChannelEmotesAdd, channel); err != nil {
+		t.Errorf("default editor adding emotes: Authorize() = %v, want nil", err)
+	}
+	if err := Authorize(context.Background(), editor, ChannelEditorsManage, channel); err != ErrAccessDenied {
+		t.Errorf("default editor managing editors: Authorize() = %v, want %v", err, ErrAccessDenied)
+	}
+}
+
+func TestAuthorizeEmoteEditByEditor(t *testing.T) {
+	owner := newUser(0, datastructure.RolePermissionDefault)
+	emote := &datastructure.Emote{ID: primitive.NewObjectID(), OwnerID: owner.ID}
+
+	tests := []struct {
+		name        string
+		permissions int64
+		err         error
+		want        error
+	}{
+		{"with edit emotes", datastructure.EditorPermissionEditEmotes, nil, nil},
+		{"with every other permission", datastructure.EditorPermissionAll &^ datastructure.EditorPermissionEditEmotes, nil, ErrAccessDenied},
+		{"not an editor", 0, nil, ErrAccessDenied},
+		{"store failing", 0, ErrInternal, ErrInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			editor := newUser(0, datastructure.RolePermissionDefault)
+			SetStore(&fakeStore{
+				editors: map[editorKey]int64{{owner.ID, editor.ID}: tt.permissions},
+				err:     tt.err,
+			})
+
+			if got := Authorize(context.Background(), editor, EmoteEdit, emote); got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsAbove(t *testing.T) {
+	tests := []struct {
+		name  string
+		actor *datastructure.User
+		role  *datastructure.Role
+		want  bool
+	}{
+		{"higher", newUser(10, 0), &datastructure.Role{Position: 5}, true},
+		{"equal", newUser(10, 0), &datastructure.Role{Position: 10}, false},
+		{"lower", newUser(10, 0), &datastructure.Role{Position: 15}, false},
+		{"no role", &datastructure.User{}, &datastructure.Role{Position: -1}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsAbove(tt.actor, tt.role); got != tt.want {
+				t.Errorf("IsAbove() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanGrant(t *testing.T) {
+	held := datastructure.RolePermissionEmoteCreate | datastructure.RolePermissionManageRoles
+	denied := newUser(10, held|datastructure.RolePermissionBanUsers)
+	denied.Role.Denied = datastructure.RolePermissionBanUsers
+
+	tests := []struct {
+		name        string
+		actor       *datastructure.User
+		permissions int64
+		want        bool
+	}{
+		{"nothing", newUser(10, held), 0, true},
+		{"subset", newUser(10, held), datastructure.RolePermissionEmoteCreate, true},
+		{"all held", newUser(10, held), held, true},
+		{"not held", newUser(10, held), datastructure.RolePermissionBanUsers, false},
+		{"held and not held", newUser(10, held), held | datastructure.RolePermissionBanUsers, false},
+		{"denied", denied, datastructure.RolePermissionBanUsers, false},
+		{"administrator", newUser(10, datastructure.RolePermissionAdministrator), datastructure.RolePermissionAll, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanGrant(tt.actor, tt.permissions); got != tt.want {
+				t.Errorf("CanGrant() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCanGrantEditor(t *testing.T) {
+	editor := newUser(0, datastructure.RolePermissionDefault)
+	held := datastructure.EditorPermissionAddEmotes | datastructure.EditorPermissionManageEditors
+	channel := newChannel(editor, held)
+
+	tests := []struct {
+		name        string
+		actor       *datastructure.User
+		permissions int64
+		want        bool
+	}{
+		{"owner", channel, datastructure.EditorPermissionAll, true},
+		{"moderator", newUser(10, datastructure.RolePermissionManageUsers), datastructure.EditorPermissionAll, true},
+		{"editor granting held", editor, held, true},
+		{"editor granting subset", editor, datastructure.EditorPermissionAddEmotes, true},
+		{"editor granting not held", editor, datastructure.EditorPermissionRemoveEmotes, false},
+		{"editor granting held and not held", editor, held | datastructure.EditorPermissionEditEmotes, false},
+		{"stranger", newUser(0, datastructure.RolePermissionDefault), 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CanGrantEditor(tt.actor, channel, tt.permissions); got != tt.want {
+				t.Errorf("CanGrantEditor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuthorizeAccessToken(t *testing.T) {
+	SetStore(&fakeStore{})
+
+	owner := newUser(0, datastructure.RolePermissionDefault)
+	emote := &datastructure.Emote{ID: primitive.NewObjectID(), OwnerID: owner.ID}
+
+	tests := []struct {
+		action   Action
+		resource interface{}
+		scope    int64
+	}{
+		{EmoteEdit, emote, datastructure.AccessTokenScopeEmotes},
+		{EmoteDetailsView, emote, datastructure.AccessTokenScopeEmotes},
+		{ChannelEdit, owner, datastructure.AccessTokenScopeChannelEmotes},
+		{ChannelEmotesAdd, owner, datastructure.AccessTokenScopeChannelEmotes},
+		{ChannelEmotesRemove, owner, datastructure.AccessTokenScopeChannelEmotes},
+		{ChannelEmotesSet, owner, datastructure.AccessTokenScopeChannelEmotes},
+		{ChannelEmoteUpload, owner, datastructure.AccessTokenScopeChannelEmotes},
+		{ChannelEditorsManage, owner, datastructure.AccessTokenScopeChannelEditors},
+		{ChannelActivityView, owner, datastructure.AccessTokenScopePrivateRead},
+		{UserPrivateView, owner, datastructure.AccessTokenScopePrivateRead},
+		{AccountEdit, nil, datastructure.AccessTokenScopeAccount},
+	}
+
+	for _, tt := range tests {
+		tokens := []struct {
+			name  string
+			token *datastructure.AccessToken
+			want  error
+		}{
+			{"no token", nil, nil},
+			{"zero scope token", &datastructure.AccessToken{Permissions: datastructure.RolePermissionAll}, ErrAccessDenied},
+			{"token with other scopes", &datastructure.AccessToken{Permissions: datastructure.RolePermissionAll, Scopes: datastructure.AccessTokenScopeAll &^ tt.scope}, ErrAccessDenied},
+			{"token with the scope", &datastructure.AccessToken{Permissions: datastructure.RolePermissionAll, Scopes: tt.scope}, nil},
+		}
+
+		for _, tok := range tokens {
+			owner.AccessToken = tok.token
+			if got := Authorize(context.Background(), owner, tt.action, tt.resource); got != tok.want {
+				t.Errorf("action %d with %s: Authorize() = %v, want %v", tt.action, tok.name, got, tok.want)
+			}
+		}
+	}
+	owner.AccessToken = nil
+
+	// Role permissions are limited to those of the token
+	moderator := newUser(10, datastructure.RolePermissionEmoteCreate|datastructure.RolePermissionEmoteEditAll|datastructure.RolePermissionBanUsers)
+	roleTests := []struct {
+		name   string
+		token  *datastructure.AccessToken
+		action Action
+		want   error
+	}{
+		{"zero permission token creates emotes", &datastructure.AccessToken{Scopes: datastructure.AccessTokenScopeAll}, EmoteCreate, ErrAccessDenied},
+		{"zero permission token moderates emotes", &datastructure.AccessToken{Scopes: datastructure.AccessTokenScopeAll}, EmoteModerate, ErrAccessDenied},
+		{"zero permission token views bans", &datastructure.AccessToken{Scopes: datastructure.AccessTokenScopeAll}, BansView, ErrAccessDenied},
+		{"token with the permission moderates emotes", &datastructure.AccessToken{Permissions: datastructure.RolePermissionEmoteEditAll}, EmoteModerate, nil},
+		{"token with another permission views bans", &datastructure.AccessToken{Permissions: datastructure.RolePermissionEmoteEditAll}, BansView, ErrAccessDenied},
+		{"token with a permission the role lacks administrates", &datastructure.AccessToken{Permissions: datastructure.RolePermissionAll}, Administrate, ErrAccessDenied},
+	}
+
+	for _, tt := range roleTests {
+		t.Run(tt.name, func(t *testing.T) {
+			moderator.AccessToken = tt.token
+			if got := Authorize(context.Background(), moderator, tt.action, nil); got != tt.want {
+				t.Errorf("Authorize() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// A zero scope token can't edit emotes even with the permission to edit all emotes
+	moderator.AccessToken = &datastructure.AccessToken{Permissions: datastructure.RolePermissionAll}
+	if got := Authorize(context.Background(), moderator, EmoteEdit, emote); got != ErrAccessDenied {
+		t.Errorf("zero scope moderator token editing emote: Authorize() = %v, want %v", got, ErrAccessDenied)
+	}
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Looks up the state authorization depends on in mongo and redis
+type Database struct{}
+
+func (Database) IsBanned(ctx context.Context, userID primitive.ObjectID) (bool, error) {
+	_, err := redis.Client.HGet(ctx, "user:bans", userID.Hex()).Result()
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	if err != nil {
+		log.Errorf("redis, err=%v", err)
+		return false, authorization.ErrInternal
+	}
+
+	return true, nil
+}
+
+func (Database) GetEditorPermissions(ctx context.Context, channelID primitive.ObjectID, userID primitive.ObjectID) (int64, error) {
+	channel := &datastructure.User{}
+	err := mongo.Database.Collection("users").FindOne(ctx, bson.M{
+		"_id":     channelID,
+		"editors": userID,
+	}, options.FindOne().SetProjection(bson.M{"editors": 1, "editor_permissions": 1})).Decode(channel)
+	if err == mongo.ErrNoDocuments {
+		return 0, nil
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return 0, authorization.ErrInternal
+	}
+
+	permissions, _ := channel.GetEditorPermissions(userID)
+	return permissions, nil
+}
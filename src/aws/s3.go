@@ -25,6 +25,8 @@ var svc = s3.New(sess)
 
 var uploader = s3manager.NewUploader(sess)
 
+var downloader = s3manager.NewDownloader(sess)
+
 func UploadFile(bucket, key string, body []byte, contentType *string) error {
 	// The session the S3 Uploader will use
 
@@ -45,6 +47,33 @@ func UploadFile(bucket, key string, body []byte, contentType *string) error {
 	return nil
 }
 
+// Upload a file which is only accessible with credentials
+func UploadPrivateFile(bucket, key string, body []byte, contentType *string) error {
+	_, err := uploader.Upload(&s3manager.UploadInput{
+		Bucket:      aws.String(bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(body),
+		ACL:         aws.String("private"),
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload file, %v", err)
+	}
+	return nil
+}
+
+func DownloadFile(bucket, key string) ([]byte, error) {
+	buf := aws.NewWriteAtBuffer([]byte{})
+	_, err := downloader.Download(buf, &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file, %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
 func Expire(bucket, key string, number int) error {
 	obj := fmt.Sprintf("deleted/%s/%vx", key, number)
 
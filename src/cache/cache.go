@@ -223,6 +223,11 @@ func FindOne(ctx context.Context, collection, commonIndex string, q interface{},
 
 // Gets the collection size then caches it in redis for some time
 func GetCollectionSize(ctx context.Context, collection string, q interface{}, opts ...*options.CountOptions) (int64, error) {
+	return GetCollectionSizeTTL(ctx, collection, q, 5*time.Minute, opts...)
+}
+
+// Get the amount of documents matching a query, cached for the specified duration
+func GetCollectionSizeTTL(ctx context.Context, collection string, q interface{}, ttl time.Duration, opts ...*options.CountOptions) (int64, error) {
 	sha1, err := genSha("collection-size", collection, q, opts)
 	if err != nil {
 		return 0, err
@@ -237,7 +242,7 @@ func GetCollectionSize(ctx context.Context, collection string, q interface{}, op
 			return 0, err
 		}
 
-		redis.Client.Set(ctx, key, count, 5*time.Minute)
+		redis.Client.Set(ctx, key, count, ttl)
 		return count, nil
 	}
 
@@ -116,6 +116,8 @@ func init() {
 
 	pflag.String("version", "1.0", "Version of the system.")
 	pflag.Int("exit_code", 0, "Status code for successful and graceful shutdown, [0-125].")
+	// Flags of others, such as those go test passes to test binaries, are left alone
+	pflag.CommandLine.ParseErrorsWhitelist.UnknownFlags = true
 	pflag.Parse()
 	checkErr(Config.BindPFlags(pflag.CommandLine))
 
@@ -99,3 +99,100 @@ func SendEmoteDelete(emote datastructure.Emote, actor datastructure.User, reason
 		return
 	}
 }
+
+func SendEmoteGlobalRequest(emote datastructure.Emote, actor datastructure.User, reason *string) {
+	if webhookID == nil || webhookToken == nil {
+		return
+	}
+
+	description := "No reason given"
+	if reason != nil && len(*reason) > 0 {
+		description = fmt.Sprintf("Reason: %s", *reason)
+	}
+
+	_, err := d.WebhookExecute(*webhookID, *webhookToken, true, &dgo.WebhookParams{
+		Content: fmt.Sprintf("**[activity]** 🌐 global status requested for emote [%s](%v) by [%s](%v)", emote.Name, utils.GetEmotePageURL(emote.ID.Hex()), actor.DisplayName, utils.GetUserPageURL(actor.ID.Hex())),
+		Embeds: []*dgo.MessageEmbed{
+			{
+				Title:       emote.Name,
+				Description: description,
+				Thumbnail: &dgo.MessageEmbedThumbnail{
+					URL: utils.GetEmoteImageURL(emote.ID.Hex()),
+				},
+				Color: toIntColor("64b5e3"),
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("discord, SendEmoteGlobalRequest, err=%v", err)
+		return
+	}
+}
+
+func SendEmoteGlobalDecision(emote datastructure.Emote, actor datastructure.User, approved bool, reason string) {
+	if webhookID == nil || webhookToken == nil {
+		return
+	}
+
+	decision, color := "denied", "e36464"
+	if approved {
+		decision, color = "approved", "24e575"
+	}
+
+	_, err := d.WebhookExecute(*webhookID, *webhookToken, true, &dgo.WebhookParams{
+		Content: fmt.Sprintf("**[activity]** 🌐 global status for emote [%s](%v) %s by [%s](%v)", emote.Name, utils.GetEmotePageURL(emote.ID.Hex()), decision, actor.DisplayName, utils.GetUserPageURL(actor.ID.Hex())),
+		Embeds: []*dgo.MessageEmbed{
+			{
+				Title:       emote.Name,
+				Description: fmt.Sprintf("Reason: %s", reason),
+				Thumbnail: &dgo.MessageEmbedThumbnail{
+					URL: utils.GetEmoteImageURL(emote.ID.Hex()),
+				},
+				Color: toIntColor(color),
+			},
+		},
+	})
+	if err != nil {
+		log.Errorf("discord, SendEmoteGlobalDecision, err=%v", err)
+		return
+	}
+}
+
+func SendEmoteDisable(emote datastructure.Emote, actor datastructure.User, reason string) {
+	if webhookID == nil || webhookToken == nil {
+		return
+	}
+
+	_, err := d.WebhookExecute(*webhookID, *webhookToken, true, &dgo.WebhookParams{
+		Content: fmt.Sprintf("**[activity]** 🚫 emote [%s](%v) disabled by [%s](%v)", emote.Name, utils.GetEmotePageURL(emote.ID.Hex()), actor.DisplayName, utils.GetUserPageURL(actor.ID.Hex())),
+		Embeds: []*dgo.MessageEmbed{
+			{Description: fmt.Sprintf("Reason: %s", reason)},
+		},
+	})
+	if err != nil {
+		log.Errorf("discord, SendEmoteDisable, err=%v", err)
+		return
+	}
+}
+
+func SendEmoteEnable(emote datastructure.Emote, actor datastructure.User, reason *string) {
+	if webhookID == nil || webhookToken == nil {
+		return
+	}
+
+	description := "No reason given"
+	if reason != nil && len(*reason) > 0 {
+		description = fmt.Sprintf("Reason: %s", *reason)
+	}
+
+	_, err := d.WebhookExecute(*webhookID, *webhookToken, true, &dgo.WebhookParams{
+		Content: fmt.Sprintf("**[activity]** ✅ emote [%s](%v) enabled by [%s](%v)", emote.Name, utils.GetEmotePageURL(emote.ID.Hex()), actor.DisplayName, utils.GetUserPageURL(actor.ID.Hex())),
+		Embeds: []*dgo.MessageEmbed{
+			{Description: description},
+		},
+	})
+	if err != nil {
+		log.Errorf("discord, SendEmoteEnable, err=%v", err)
+		return
+	}
+}
@@ -1,3 +1,8 @@
 package cache
 
+import "sync"
+
 var CachedRoles interface{}
+
+// Guards CachedRoles, which is replaced whenever a role changes
+var RolesMutex sync.RWMutex
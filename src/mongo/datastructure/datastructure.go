@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/SevenTV/ServerGo/src/configure"
 	"github.com/SevenTV/ServerGo/src/mongo/cache"
 	"github.com/SevenTV/ServerGo/src/utils"
 	log "github.com/sirupsen/logrus"
@@ -14,6 +15,7 @@ import (
 type Emote struct {
 	ID               primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
 	Name             string               `json:"name" bson:"name"`
+	NameLower        string               `json:"-" bson:"name_lower"` // The name in lower case, which search matches on
 	OwnerID          primitive.ObjectID   `json:"owner_id" bson:"owner"`
 	Visibility       int32                `json:"visibility" bson:"visibility"`
 	Mime             string               `json:"mime" bson:"mime"`
@@ -25,18 +27,46 @@ type Emote struct {
 	Height           [4]int16             `json:"height" bson:"height"` // The emote's height in pixels
 	Animated         bool                 `json:"animated" bson:"animated"`
 
-	// ChannelCount is used during the popularity sort check, generated by a pipeline.
-	// It is not used anywhere else
+	// The request for this emote to become global, if one was made
+	GlobalRequest *EmoteGlobalRequest `json:"global_request" bson:"global_request,omitempty"`
+
+	// Why the emote was disabled by a moderator, only set while the emote is disabled
+	DisableReason *string `json:"disable_reason" bson:"disable_reason,omitempty"`
+	// The status the emote had before being disabled, which it gets back once enabled again
+	StatusBeforeDisable *int32 `json:"-" bson:"status_before_disable,omitempty"`
+
+	// ChannelCount is the amount of channels which have the emote enabled, used during the popularity sort check.
+	// It is periodically recomputed by the channel count worker
 	ChannelCount          *int32     `json:"channel_count" bson:"channel_count"`
 	LastChannelCountCheck *time.Time `json:"channel_count_checked_at" bson:"channel_count_checked_at"`
 
+	// FavoriteCount is the amount of users who favorited the emote
+	FavoriteCount int32 `json:"favorite_count" bson:"favorite_count,omitempty"`
+
+	// Trending holds the net change in channels per time window, only set while the emote is being added or removed
+	Trending *EmoteTrendingScores `json:"trending" bson:"trending,omitempty"`
+
 	Owner        *User        `json:"owner" bson:"-"`
 	AuditEntries *[]*AuditLog `json:"audit_entries" bson:"-"`
 	Channels     *[]*User     `json:"channels" bson:"-"`
 	Reports      *[]*Report   `json:"reports" bson:"-"`
-	Provider     string       `json:"provider" bson:"-"`    // The service provider for the emote
-	ProviderID   *string      `json:"provider_id" bson:"-"` // The emote ID as defined by the foreign provider. Nil if 7TV
-	URLs         [][]string   `json:"urls" bson:"-"`        // Synthesized URLs to CDN for the emote
+	Provider     string       `json:"provider" bson:"-"`     // The service provider for the emote
+	ProviderID   *string      `json:"provider_id" bson:"-"`  // The emote ID as defined by the foreign provider. Nil if 7TV
+	URLs         [][]string   `json:"urls" bson:"-"`         // Synthesized URLs to CDN for the emote
+	SearchScore  *float64     `json:"search_score" bson:"-"` // How relevant the emote is to a search, only set for search results
+}
+
+// Check whether a user may see the emote
+// Deleted emotes are hidden, private and disabled emotes are only visible to their owner and moderators
+func (e *Emote) IsVisibleTo(u *User) bool {
+	if e.Status == EmoteStatusDeleted {
+		return false
+	}
+	if u != nil && (u.ID == e.OwnerID || u.HasPermission(RolePermissionEmoteEditAll)) {
+		return true
+	}
+
+	return e.Status != EmoteStatusDisabled && e.Visibility&EmoteVisibilityPrivate == 0
 }
 
 func GetEmoteURLs(emote Emote) [][]string {
@@ -73,6 +103,21 @@ const (
 	EmoteStatusLive
 )
 
+// The statuses of emotes which can be used in channels
+// A pending emote is live while it awaits review for global status
+var EmoteStatusesUsable = []int32{EmoteStatusLive, EmoteStatusPending}
+
+// A request for an emote to become global
+type EmoteGlobalRequest struct {
+	RequestedByID  primitive.ObjectID  `json:"requested_by_id" bson:"requested_by_id"`
+	RequestedAt    time.Time           `json:"requested_at" bson:"requested_at"`
+	Reason         *string             `json:"reason" bson:"reason"`                   // Why the requester wants the emote to be global
+	Approved       *bool               `json:"approved" bson:"approved"`               // The decision on the request. Nil while it awaits review
+	DecidedByID    *primitive.ObjectID `json:"decided_by_id" bson:"decided_by_id"`     // The moderator who decided on the request
+	DecidedAt      *time.Time          `json:"decided_at" bson:"decided_at"`           // When the request was decided on
+	DecisionReason *string             `json:"decision_reason" bson:"decision_reason"` // Why the request was approved or denied
+}
+
 type User struct {
 	ID           primitive.ObjectID   `json:"_id" bson:"_id,omitempty"`
 	Email        string               `json:"email" bson:"email"`
@@ -82,6 +127,13 @@ type User struct {
 	RoleID       *primitive.ObjectID  `json:"role_id" bson:"role"`
 	TokenVersion string               `json:"token_version" bson:"token_version"`
 
+	// Additional channel emote slots granted to this user on top of their role's
+	EmoteSlotGrants []*EmoteSlotGrant `json:"emote_slot_grants" bson:"emote_slot_grants"`
+
+	// The permissions of each editor, by their hex id
+	// Editors without an entry have the default editor permissions
+	EditorPermissions map[string]int64 `json:"editor_permissions" bson:"editor_permissions,omitempty"`
+
 	// Twitch Data
 	TwitchID        string    `json:"twitch_id" bson:"id"`
 	DisplayName     string    `json:"display_name" bson:"display_name"`
@@ -102,27 +154,85 @@ type User struct {
 	AuditEntries *[]*AuditLog `json:"audit_entries" bson:"-"`
 	Reports      *[]*Report   `json:"reports" bson:"-"`
 	Bans         *[]*Ban      `json:"bans" bson:"-"`
+
+	AccessToken *AccessToken `json:"-" bson:"-"` // The personal access token the User authenticated with, if any
 }
 
 // Test whether a User has a permission flag
 func (u *User) HasPermission(flag int64) bool {
-	var allowed int64 = 0
-	var denied int64 = 0
-	if u != nil {
-		allowed = u.Role.Allowed
-		denied = u.Role.Denied
-	}
-
 	if !utils.IsPowerOfTwo(flag) { // Don't evaluate if flag is invalid
 		log.Errorf("HasPermission, err=flag is not power of two (%s)", fmt.Sprint(flag))
 		return false
 	}
 
-	// Get the sum with denied permissions removed from the bitset
-	sum := utils.BitField.RemoveBits(allowed, denied)
+	sum := u.GetPermissions()
 	return utils.BitField.HasBits(sum, flag) || utils.BitField.HasBits(sum, RolePermissionAdministrator)
 }
 
+// Get the permissions of a User, with the denied permissions of their role removed
+// Users authenticated with an access token are limited to its permissions
+func (u *User) GetPermissions() int64 {
+	if u == nil || u.Role == nil {
+		return 0
+	}
+
+	sum := utils.BitField.RemoveBits(u.Role.Allowed, u.Role.Denied)
+	if u.AccessToken != nil {
+		sum &= u.AccessToken.Permissions
+	}
+	return sum
+}
+
+// Get the amount of channel emote slots available to a User
+// This is the slot capacity of their role plus any unexpired grants
+func (u *User) GetEmoteSlots() int32 {
+	role := u.Role
+	if role == nil {
+		r := GetRole(context.Background(), u.RoleID)
+		role = &r
+	}
+
+	slots := role.ChannelEmoteSlots
+	if slots <= 0 {
+		slots = int32(configure.Config.GetInt("limits.meta.channel_emote_slots"))
+	}
+
+	now := time.Now()
+	for _, g := range u.EmoteSlotGrants {
+		if g == nil || (g.ExpireAt != nil && g.ExpireAt.Before(now)) {
+			continue
+		}
+
+		slots += g.Amount
+	}
+
+	return slots
+}
+
+// Get the permissions of an editor of a User's channel, and whether they are an editor at all
+func (u *User) GetEditorPermissions(editorID primitive.ObjectID) (int64, bool) {
+	for _, id := range u.EditorIDs {
+		if id != editorID {
+			continue
+		}
+
+		if p, ok := u.EditorPermissions[editorID.Hex()]; ok {
+			return p, true
+		}
+		return EditorPermissionDefault, true
+	}
+
+	return 0, false
+}
+
+type EmoteSlotGrant struct {
+	ID        primitive.ObjectID `json:"id" bson:"id"`
+	Amount    int32              `json:"amount" bson:"amount"`         // The amount of slots granted
+	ExpireAt  *time.Time         `json:"expire_at" bson:"expire_at"`   // When the grant stops applying. Nil if it never expires
+	Reason    string             `json:"reason" bson:"reason"`         // Why the slots were granted
+	GrantedBy primitive.ObjectID `json:"granted_by" bson:"granted_by"` // The user who granted the slots
+}
+
 type Role struct {
 	ID       primitive.ObjectID `json:"id" bson:"_id"`
 	Name     string             `json:"name" bson:"name"`
@@ -130,6 +240,10 @@ type Role struct {
 	Color    int32              `json:"color" bson:"color"`
 	Allowed  int64              `json:"allowed" bson:"allowed"`
 	Denied   int64              `json:"denied" bson:"denied"`
+
+	// The amount of channel emote slots members of this role have
+	// 0 falls back to the default defined in config (limits.meta.channel_emote_slots)
+	ChannelEmoteSlots int32 `json:"channel_emote_slots" bson:"channel_emote_slots"`
 }
 
 // Get a cached role by ID
@@ -141,7 +255,9 @@ func GetRole(ctx context.Context, id *primitive.ObjectID) Role {
 	var found bool
 	var role Role
 
-	roles := cache.CachedRoles.([]Role)
+	cache.RolesMutex.RLock()
+	roles, _ := cache.CachedRoles.([]Role)
+	cache.RolesMutex.RUnlock()
 
 	for _, r := range roles {
 		if r.ID.Hex() != id.Hex() {
@@ -175,6 +291,27 @@ const (
 	RolePermissionDefault int64 = (RolePermissionEmoteCreate | RolePermissionEmoteEditOwned | RolePermissionCreateReports | RolePermissionManageEditors) // Default permissions for users without a role
 )
 
+const (
+	AccessTokenScopeEmotes         int64 = 1 << iota // 1 - Allows editing, deleting and restoring emotes, and seeing their private details
+	AccessTokenScopeChannelEmotes                    // 2 - Allows adding, removing, replacing, uploading and scheduling the emotes of channels
+	AccessTokenScopeChannelEditors                   // 4 - Allows managing the editors of channels
+	AccessTokenScopePrivateRead                      // 8 - Allows seeing the private details of users and the activity of channels
+	AccessTokenScopeAccount                          // 16 - Allows managing favorites, notifications and editor invitations, and leaving channels
+
+	AccessTokenScopeAll int64 = (1 << iota) - 1 // Sum of all access token scopes combined
+)
+
+const (
+	EditorPermissionAddEmotes     int64 = 1 << iota // 1 - Allows adding emotes to the channel
+	EditorPermissionRemoveEmotes                    // 2 - Allows removing emotes from the channel
+	EditorPermissionUploadEmotes                    // 4 - Allows uploading emotes on behalf of the channel
+	EditorPermissionEditEmotes                      // 8 - Allows editing, deleting and restoring the emotes of the channel
+	EditorPermissionManageEditors                   // 16 - Allows adding and removing other editors
+
+	EditorPermissionAll     int64 = (1 << iota) - 1                                                                                                        // Sum of all editor permissions combined
+	EditorPermissionDefault int64 = (EditorPermissionAddEmotes | EditorPermissionRemoveEmotes | EditorPermissionUploadEmotes | EditorPermissionEditEmotes) // Default permissions of editors
+)
+
 const (
 	UserRankDefault   int32 = 0
 	UserRankModerator int32 = 1
@@ -191,12 +328,37 @@ type Ban struct {
 }
 
 type AuditLog struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Type      int32              `json:"type" bson:"type"`
-	Target    *Target            `json:"target" bson:"target"`
-	Changes   []*AuditLogChange  `json:"changes" bson:"changes"`
-	Reason    *string            `json:"reason" bson:"reason"`
-	CreatedBy primitive.ObjectID `json:"action_user" bson:"action_user"`
+	ID        primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	Type      int32               `json:"type" bson:"type"`
+	Target    *Target             `json:"target" bson:"target"`
+	Changes   []*AuditLogChange   `json:"changes" bson:"changes"`
+	Reason    *string             `json:"reason" bson:"reason"`
+	CreatedBy primitive.ObjectID  `json:"action_user" bson:"action_user"`
+	Reverts   *primitive.ObjectID `json:"reverts,omitempty" bson:"reverts,omitempty"` // The entry undone by this entry, set for reverts
+
+	// Where the action came from
+	// Never serialized to JSON, as audit entries are shown publicly on emotes, users and reports
+	Request *AuditLogRequest `json:"-" bson:"request,omitempty"`
+}
+
+// The request an audited action was performed with
+type AuditLogRequest struct {
+	IP        string `json:"ip" bson:"ip"`
+	Forwarded bool   `json:"forwarded" bson:"forwarded"` // Whether the IP was forwarded by a trusted proxy, rather than being the connection's
+	UserAgent string `json:"user_agent" bson:"user_agent"`
+	RequestID string `json:"request_id" bson:"request_id"`
+	Node      string `json:"node" bson:"node"`
+}
+
+// A file of audit log entries moved out of the database by the retention policy
+type AuditArchiveFile struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id"`
+	Key       string             `json:"key" bson:"key"`           // The storage key of the compressed NDJSON file
+	Types     []int32            `json:"types" bson:"types"`       // The types of the entries in the file
+	FirstID   primitive.ObjectID `json:"first_id" bson:"first_id"` // The id of the oldest entry in the file
+	LastID    primitive.ObjectID `json:"last_id" bson:"last_id"`   // The id of the newest entry in the file
+	Count     int32              `json:"count" bson:"count"`       // The amount of entries in the file
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
 }
 
 type Target struct {
@@ -223,6 +385,171 @@ type Report struct {
 	AuditEntries *[]*AuditLog `json:"audit_entries" bson:"-"`
 }
 
+// A message for a user about something that happened to them or their emotes
+type Notification struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Message   string             `json:"message" bson:"message"`
+	Target    *Target            `json:"target" bson:"target"`
+	Read      bool               `json:"read" bson:"read"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// A sample of an emote's channel count, kept to chart adoption over time
+type EmoteChannelCountSample struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	EmoteID   primitive.ObjectID `json:"emote_id" bson:"emote_id"`
+	Count     int32              `json:"count" bson:"count"`
+	Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+}
+
+// An emote being added to or removed from a channel
+type EmoteChannelEvent struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	EmoteID   primitive.ObjectID `json:"emote_id" bson:"emote_id"`
+	ChannelID primitive.ObjectID `json:"channel_id" bson:"channel_id"`
+	Added     bool               `json:"added" bson:"added"`
+	Timestamp time.Time          `json:"timestamp" bson:"timestamp"`
+}
+
+// The net change in channels of an emote over the trending windows
+type EmoteTrendingScores struct {
+	Day       int32     `json:"day" bson:"day"`
+	Week      int32     `json:"week" bson:"week"`
+	Month     int32     `json:"month" bson:"month"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at"`
+}
+
+// Get the score of the specified trending window
+func (s *EmoteTrendingScores) Score(window string) int32 {
+	if s == nil {
+		return 0
+	}
+
+	switch window {
+	case EmoteTrendingWindowDay:
+		return s.Day
+	case EmoteTrendingWindowMonth:
+		return s.Month
+	default:
+		return s.Week
+	}
+}
+
+const (
+	EmoteTrendingWindowDay   = "day"
+	EmoteTrendingWindowWeek  = "week"
+	EmoteTrendingWindowMonth = "month"
+)
+
+// An emote bookmarked by a user
+type EmoteFavorite struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	EmoteID   primitive.ObjectID `json:"emote_id" bson:"emote_id"`
+	CreatedAt time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// A tag which is replaced by another when written, kept by moderators
+type TagSynonym struct {
+	Synonym     string             `json:"synonym" bson:"_id"`
+	Tag         string             `json:"tag" bson:"tag"`
+	CreatedByID primitive.ObjectID `json:"created_by_id" bson:"created_by_id"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// The amount of emotes using a tag
+type TagCount struct {
+	Tag   string `json:"tag" bson:"_id"`
+	Count int32  `json:"count" bson:"count"`
+}
+
+// A change to the emotes of a channel which will be applied at a later time
+type ChannelEmoteSchedule struct {
+	ID          primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	ChannelID   primitive.ObjectID   `json:"channel_id" bson:"channel_id"`
+	Action      string               `json:"action" bson:"action"` // One of the ChannelEmoteScheduleAction values
+	EmoteIDs    []primitive.ObjectID `json:"emote_ids" bson:"emote_ids"`
+	ExecuteAt   time.Time            `json:"execute_at" bson:"execute_at"`
+	Status      int32                `json:"status" bson:"status"`
+	Reason      *string              `json:"reason" bson:"reason"`
+	CreatedByID primitive.ObjectID   `json:"created_by_id" bson:"created_by_id"`
+	CreatedAt   time.Time            `json:"created_at" bson:"created_at"`
+	ClaimedBy   string               `json:"-" bson:"claimed_by"`            // The node which claimed the schedule for execution
+	ClaimedAt   *time.Time           `json:"-" bson:"claimed_at"`            // The time at which the schedule was claimed
+	ExecutedAt  *time.Time           `json:"executed_at" bson:"executed_at"` // The time at which the schedule finished executing or was cancelled
+	Error       *string              `json:"error" bson:"error"`             // Why the schedule failed, if it did
+}
+
+// A named token a user created for bots and scripts, limited to some of their permissions
+type AccessToken struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Name        string             `json:"name" bson:"name"`
+	Permissions int64              `json:"permissions" bson:"permissions"` // The role permissions requests made with the token are limited to
+	Scopes      int64              `json:"scopes" bson:"scopes"`           // What the token may do with what the user owns or edits, see AccessTokenScope
+	ExpireAt    time.Time          `json:"expire_at" bson:"expire_at"`
+	LastUsedAt  *time.Time         `json:"last_used_at" bson:"last_used_at"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+}
+
+// An invitation for a user to become an editor of a channel, which they may accept or decline
+type EditorInvitation struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ChannelID   primitive.ObjectID `json:"channel_id" bson:"channel_id"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Permissions int64              `json:"permissions" bson:"permissions"` // The editor permissions granted once accepted
+	Status      int32              `json:"status" bson:"status"`
+	Reason      *string            `json:"reason" bson:"reason"`
+	CreatedByID primitive.ObjectID `json:"created_by_id" bson:"created_by_id"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	AnsweredAt  *time.Time         `json:"answered_at" bson:"answered_at"` // The time at which the invitation was accepted, declined or cancelled
+}
+
+// A named set of emotes which are global while the set is active
+type GlobalEmoteSet struct {
+	ID          primitive.ObjectID   `json:"id" bson:"_id,omitempty"`
+	Name        string               `json:"name" bson:"name"`
+	EmoteIDs    []primitive.ObjectID `json:"emote_ids" bson:"emotes"`
+	StartAt     *time.Time           `json:"start_at" bson:"start_at"` // When the set becomes active. Nil if it is active from its creation
+	EndAt       *time.Time           `json:"end_at" bson:"end_at"`     // When the set stops being active. Nil if it never does
+	CreatedByID primitive.ObjectID   `json:"created_by_id" bson:"created_by_id"`
+	CreatedAt   time.Time            `json:"created_at" bson:"created_at"`
+}
+
+// Test whether a GlobalEmoteSet is active at the specified time
+func (s *GlobalEmoteSet) IsActive(t time.Time) bool {
+	if s.StartAt != nil && t.Before(*s.StartAt) {
+		return false
+	}
+	if s.EndAt != nil && !t.Before(*s.EndAt) {
+		return false
+	}
+
+	return true
+}
+
+const (
+	ChannelEmoteScheduleActionAdd    = "add"    // Add the emotes to the channel
+	ChannelEmoteScheduleActionRemove = "remove" // Remove the emotes from the channel
+	ChannelEmoteScheduleActionSet    = "set"    // Replace the channel's emotes with the emotes
+)
+
+const (
+	ChannelEmoteScheduleStatusPending int32 = iota
+	ChannelEmoteScheduleStatusRunning
+	ChannelEmoteScheduleStatusDone
+	ChannelEmoteScheduleStatusFailed
+	ChannelEmoteScheduleStatusCancelled
+)
+
+const (
+	EditorInvitationStatusPending int32 = iota
+	EditorInvitationStatusAccepted
+	EditorInvitationStatusDeclined
+	EditorInvitationStatusCancelled // The invitation was withdrawn by removing the editor
+)
+
 const (
 	AuditLogTypeEmoteCreate int32 = 1
 	AuditLogTypeEmoteDelete int32 = iota
@@ -255,3 +582,60 @@ const (
 	AuditLogTypeReport      int32 = 71
 	AuditLogTypeReportClear int32 = iota
 )
+
+const (
+	AuditLogTypeUserChannelEmoteImport int32 = 41 + iota
+	AuditLogTypeUserEmoteSlotGrant
+	AuditLogTypeUserEmoteSlotRevoke
+	AuditLogTypeUserChannelEmoteSet
+	AuditLogTypeUserChannelEmoteScheduleCreate
+	AuditLogTypeUserChannelEmoteScheduleCancel
+)
+
+const (
+	AuditLogTypeGlobalEmoteSetCreate int32 = 61 + iota
+	AuditLogTypeGlobalEmoteSetEdit
+	AuditLogTypeGlobalEmoteSetDelete
+)
+
+const (
+	AuditLogTypeEmoteGlobalRequest int32 = 81 + iota
+	AuditLogTypeEmoteGlobalApprove
+	AuditLogTypeEmoteGlobalDeny
+	AuditLogTypeEmoteEnable
+)
+
+const (
+	AuditLogTypeTagSynonymSet int32 = 91 + iota
+	AuditLogTypeTagSynonymRemove
+)
+
+const (
+	AuditLogTypeAuditRevert int32 = 101 + iota
+)
+
+const (
+	AuditLogTypeRoleCreate int32 = 111 + iota
+	AuditLogTypeRoleEdit
+	AuditLogTypeRoleDelete
+	AuditLogTypeRoleReorder
+	AuditLogTypeUserRoleAssign
+	AuditLogTypeUserRoleUnassign
+)
+
+const (
+	AuditLogTypeUserChannelEditorPermissionsSet int32 = 121 + iota
+)
+
+const (
+	AuditLogTypeUserChannelEditorInvite int32 = 131 + iota
+	AuditLogTypeUserChannelEditorInviteAccept
+	AuditLogTypeUserChannelEditorInviteDecline
+	AuditLogTypeUserChannelEditorInviteCancel
+	AuditLogTypeUserChannelEditorLeave
+)
+
+const (
+	AuditLogTypeUserAccessTokenCreate int32 = 141 + iota
+	AuditLogTypeUserAccessTokenRevoke
+)
@@ -38,6 +38,35 @@ func NewUpdateOneModel() *mongo.UpdateOneModel {
 	return mongo.NewUpdateOneModel()
 }
 
+func NewReplaceOneModel() *mongo.ReplaceOneModel {
+	return mongo.NewReplaceOneModel()
+}
+
+// The indexes of the emotes collection
+var EmoteIndexes = []mongo.IndexModel{
+	{Keys: bson.M{"name": 1}},
+	{Keys: bson.M{"name_lower": 1}},
+	{Keys: bson.M{"owner_id": 1}},
+	{Keys: bson.M{"tags": 1}},
+	{Keys: bson.M{"status": 1}},
+	{Keys: bson.M{"last_modified_date": 1}, Options: options.Index().SetExpireAfterSeconds(int32(time.Hour * 24 * 21 / time.Second)).SetPartialFilterExpression(bson.M{
+		"status": datastructure.EmoteStatusDeleted,
+	})},
+	{Keys: bson.M{"channel_count_checked_at": 1}},
+	{Keys: bson.M{"owner": 1}},
+	{Keys: bson.M{"animated": 1}},
+	{Keys: bson.M{"width.0": 1}},
+	{Keys: bson.M{"channel_count": -1}},
+	// Search sort modes: age, popularity and trending, every search matches on status
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "_id", Value: -1}}},
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "channel_count", Value: -1}, {Key: "_id", Value: -1}}},
+	{Keys: bson.D{{Key: "status", Value: 1}, {Key: "trending.week", Value: -1}, {Key: "channel_count", Value: -1}, {Key: "_id", Value: -1}}},
+	{Keys: bson.M{"trending.day": -1}, Options: options.Index().SetSparse(true)},
+	{Keys: bson.M{"trending.week": -1}, Options: options.Index().SetSparse(true)},
+	{Keys: bson.M{"trending.month": -1}, Options: options.Index().SetSparse(true)},
+	{Keys: bson.M{"trending.updated_at": 1}, Options: options.Index().SetSparse(true)},
+}
+
 func init() {
 	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*25)
 	defer cancel()
@@ -58,16 +87,7 @@ func init() {
 
 	Database = client.Database(configure.Config.GetString("mongo_db"))
 
-	_, err = Database.Collection("emotes").Indexes().CreateMany(ctx, []mongo.IndexModel{
-		{Keys: bson.M{"name": 1}},
-		{Keys: bson.M{"owner_id": 1}},
-		{Keys: bson.M{"tags": 1}},
-		{Keys: bson.M{"status": 1}},
-		{Keys: bson.M{"last_modified_date": 1}, Options: options.Index().SetExpireAfterSeconds(int32(time.Hour * 24 * 21 / time.Second)).SetPartialFilterExpression(bson.M{
-			"status": datastructure.EmoteStatusDeleted,
-		})},
-		{Keys: bson.M{"channel_count_checked_at": 1}},
-	})
+	_, err = Database.Collection("emotes").Indexes().CreateMany(ctx, EmoteIndexes)
 	if err != nil {
 		log.Errorf("mongodb, err=%v", err)
 		return
@@ -78,6 +98,7 @@ func init() {
 		{Keys: bson.M{"login": 1}, Options: options.Index().SetUnique(true)},
 		{Keys: bson.M{"rank": 1}},
 		{Keys: bson.M{"editors": 1}},
+		{Keys: bson.M{"emotes": 1}},
 	})
 	if err != nil {
 		log.Errorf("mongodb, err=%v", err)
@@ -98,6 +119,27 @@ func init() {
 		{Keys: bson.M{"type": 1}},
 		{Keys: bson.M{"target.type": 1}},
 		{Keys: bson.M{"target.id": 1}},
+		{Keys: bson.D{{Key: "target.id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.M{"action_user": 1}},
+		{Keys: bson.M{"reverts": 1}, Options: options.Index().SetSparse(true)},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	_, err = Database.Collection("audit_archive").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"type": 1}},
+		{Keys: bson.M{"action_user": 1}},
+		{Keys: bson.D{{Key: "target.id", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	_, err = Database.Collection("audit_archive_files").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"last_id": -1}},
 	})
 	if err != nil {
 		log.Errorf("mongodb, err=%v", err)
@@ -108,6 +150,89 @@ func init() {
 		{Keys: bson.M{"reporter_id": 1}},
 		{Keys: bson.M{"target.type": 1}},
 		{Keys: bson.M{"target.id": 1}},
+		{Keys: bson.D{{Key: "target.id", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	_, err = Database.Collection("channel_emote_schedules").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "execute_at", Value: 1}}},
+		{Keys: bson.M{"channel_id": 1}},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	// Access tokens are removed once expired
+	_, err = Database.Collection("access_tokens").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"user_id": 1}},
+		{Keys: bson.M{"expire_at": 1}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	// Only one invitation per channel and user may be pending
+	_, err = Database.Collection("editor_invitations").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "status", Value: 1}}},
+		{
+			Keys:    bson.D{{Key: "channel_id", Value: 1}, {Key: "user_id", Value: 1}},
+			Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"status": datastructure.EditorInvitationStatusPending}),
+		},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	_, err = Database.Collection("emote_channel_counts").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "emote_id", Value: 1}, {Key: "timestamp", Value: 1}}},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	// Channel events are only kept as long as the longest trending window
+	_, err = Database.Collection("emote_channel_events").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"timestamp": 1}, Options: options.Index().SetExpireAfterSeconds(int32(time.Hour * 24 * 31 / time.Second))},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	_, err = Database.Collection("emote_favorites").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "emote_id", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	_, err = Database.Collection("emote_tags").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	_, err = Database.Collection("tag_synonyms").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.M{"tag": 1}},
+	})
+	if err != nil {
+		log.Errorf("mongodb, err=%v", err)
+		return
+	}
+
+	_, err = Database.Collection("notifications").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "read", Value: 1}}},
 	})
 	if err != nil {
 		log.Errorf("mongodb, err=%v", err)
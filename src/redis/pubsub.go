@@ -42,3 +42,8 @@ type PubSubPayloadUserEmotes struct {
 	ID      string `json:"id"`
 	Actor   string `json:"actor"`
 }
+
+type PubSubPayloadGlobalEmotes struct {
+	Removed bool   `json:"removed"`
+	ID      string `json:"id"`
+}
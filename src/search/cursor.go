@@ -0,0 +1,102 @@
+package search
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var ErrInvalidCursor = fmt.Errorf("invalid cursor")
+
+// The content of a cursor: the sort keys of a search and the values of the last result for each key
+type cursor struct {
+	Keys   []string `bson:"k"`
+	Values bson.A   `bson:"v"`
+}
+
+// Encode the position after a result into an opaque cursor
+func EncodeCursor(sort bson.D, values bson.A) (string, error) {
+	keys := make([]string, len(sort))
+	for i, e := range sort {
+		keys[i] = e.Key
+	}
+
+	b, err := bson.Marshal(&cursor{Keys: keys, Values: values})
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Decode a cursor into the values of the result it points after
+// The cursor must have been created for the same sort
+func DecodeCursor(sort bson.D, s string) (bson.A, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	c := &cursor{}
+	if err := bson.Unmarshal(b, c); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if len(c.Keys) != len(sort) || len(c.Values) != len(sort) {
+		return nil, ErrInvalidCursor
+	}
+	for i, e := range sort {
+		if c.Keys[i] != e.Key {
+			return nil, ErrInvalidCursor
+		}
+	}
+
+	return c.Values, nil
+}
+
+// Get the filter matching every document sorted after the specified values
+//
+// A document comes after the values if it is equal on the first keys and after on the next one.
+// Null and missing values are sorted before every other value, as mongo does
+func KeysetFilter(sort bson.D, values bson.A) bson.M {
+	branches := bson.A{}
+	for i, e := range sort {
+		branch := bson.M{}
+		for j := 0; j < i; j++ {
+			branch[sort[j].Key] = values[j]
+		}
+
+		ascending := fmt.Sprint(e.Value) != "-1"
+		switch {
+		case values[i] == nil && ascending:
+			branch[e.Key] = bson.M{"$ne": nil}
+		case values[i] == nil:
+			continue // Nothing is sorted after null when descending
+		case ascending:
+			branch[e.Key] = bson.M{"$gt": values[i]}
+		default:
+			branch[e.Key] = bson.M{"$lt": values[i]}
+		}
+
+		// Descending, null values come after every other value
+		if values[i] != nil && !ascending {
+			branch = bson.M{"$or": bson.A{branch, withKey(branch, e.Key, nil)}}
+		}
+		branches = append(branches, branch)
+	}
+
+	if len(branches) == 0 {
+		return bson.M{"_id": bson.M{"$exists": false}}
+	}
+	return bson.M{"$or": branches}
+}
+
+func withKey(m bson.M, key string, value interface{}) bson.M {
+	out := make(bson.M, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	out[key] = value
+
+	return out
+}
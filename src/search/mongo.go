@@ -0,0 +1,190 @@
+package search
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/cache"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Search emotes in mongo, scoring matches with an aggregation
+//
+// The pipeline always matches first so that the filter and the sort can be served by the compound indexes of each sort mode,
+// the relevance score is only computed for the matching emotes, or only for the page returned when it isn't sorted on
+type MongoBackend struct{}
+
+// How long the amount of emotes matching a search is cached
+const countCacheDuration = time.Second * 30
+
+// An emote returned by the aggregation, along with its relevance score and sort values
+type mongoEmoteHit struct {
+	datastructure.Emote `bson:",inline"`
+	Score               float64 `bson:"search_score"`
+	SortValues          bson.A  `bson:"search_sort"`
+}
+
+func (*MongoBackend) SearchEmotes(ctx context.Context, q EmoteQuery) ([]*EmoteHit, error) {
+	text := strings.ToLower(strings.TrimSpace(q.Text))
+	sort := mongoEmoteSort(q, text)
+
+	pipeline, err := mongoEmotePipeline(q, text, sort)
+	if err != nil {
+		return nil, err
+	}
+
+	cur, err := mongo.Database.Collection("emotes").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []*mongoEmoteHit{}
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	hits := make([]*EmoteHit, len(docs))
+	for i, d := range docs {
+		emote := d.Emote
+		if text != "" {
+			score := d.Score
+			emote.SearchScore = &score
+		}
+
+		c, err := EncodeCursor(sort, d.SortValues)
+		if err != nil {
+			return nil, err
+		}
+		hits[i] = &EmoteHit{Emote: &emote, Cursor: c}
+	}
+
+	return hits, nil
+}
+
+// Get the aggregation returning a page of the emotes matching a query
+//
+// Unless the results are ordered by relevance, the page is cut before scoring,
+// so that the keyset filter and the sort are served by the index and only the emotes returned are scored
+func mongoEmotePipeline(q EmoteQuery, text string, sort bson.D) (mongo.Pipeline, error) {
+	byScore := false
+	for _, e := range sort {
+		if e.Key == "search_score" {
+			byScore = true
+		}
+	}
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: mongoEmoteMatch(q.Filter, text)}},
+	}
+	if text != "" && byScore {
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"search_score": mongoEmoteScore(text)}}})
+	}
+	if q.After != "" {
+		values, err := DecodeCursor(sort, q.After)
+		if err != nil {
+			return nil, err
+		}
+		pipeline = append(pipeline, bson.D{{Key: "$match", Value: KeysetFilter(sort, values)}})
+	}
+
+	pipeline = append(pipeline, bson.D{{Key: "$sort", Value: sort}})
+	if q.Skip > 0 {
+		pipeline = append(pipeline, bson.D{{Key: "$skip", Value: q.Skip}})
+	}
+	pipeline = append(pipeline, bson.D{{Key: "$limit", Value: q.Limit}})
+	if text != "" && !byScore {
+		pipeline = append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"search_score": mongoEmoteScore(text)}}})
+	}
+
+	sortValues := bson.A{}
+	for _, e := range sort {
+		sortValues = append(sortValues, "$"+e.Key)
+	}
+	return append(pipeline, bson.D{{Key: "$addFields", Value: bson.M{"search_sort": sortValues}}}), nil
+}
+
+// The amount of matching emotes is cached briefly, as an exact count would scan every match on each request
+func (*MongoBackend) CountEmotes(ctx context.Context, q EmoteQuery) (int64, error) {
+	return cache.GetCollectionSizeTTL(ctx, "emotes", mongoEmoteMatch(q.Filter, strings.ToLower(strings.TrimSpace(q.Text))), countCacheDuration)
+}
+
+// Get the order of the results, ending with the emote ID so that every result has a distinct position for cursors
+func mongoEmoteSort(q EmoteQuery, text string) bson.D {
+	sort := bson.D{}
+	switch {
+	case len(q.Sort) > 0:
+		sort = append(sort, q.Sort...)
+	case text != "":
+		sort = bson.D{{Key: "search_score", Value: -1}, {Key: "channel_count", Value: -1}}
+	}
+
+	for _, e := range sort {
+		if e.Key == "_id" {
+			return sort
+		}
+	}
+	direction := interface{}(1)
+	if len(sort) > 0 {
+		direction = sort[len(sort)-1].Value
+	}
+
+	return append(sort, bson.E{Key: "_id", Value: direction})
+}
+
+// Get the filter matching every emote which is a candidate for the text
+//
+// The lowercased name must contain every character of the text in order, which includes exact, prefix and substring matches,
+// or a tag must start with the text. The patterns are case sensitive on fields which are already lowercased,
+// so that they are checked against the keys of the indexes on name_lower and tags instead of every emote
+func mongoEmoteMatch(filter bson.M, text string) bson.M {
+	match := bson.M{}
+	for k, v := range filter {
+		match[k] = v
+	}
+	if text == "" {
+		return match
+	}
+
+	candidates := bson.M{"$or": bson.A{
+		bson.M{"name_lower": bson.M{"$regex": fuzzyPattern(text)}},
+		bson.M{"tags": bson.M{"$regex": "^" + regexp.QuoteMeta(text)}},
+	}}
+	if and, ok := match["$and"].(bson.A); ok {
+		match["$and"] = append(append(bson.A{}, and...), candidates)
+	} else {
+		match["$and"] = bson.A{candidates}
+	}
+
+	return match
+}
+
+// Get the expression scoring how well an emote matches the text
+// Every emote scored was matched by mongoEmoteMatch, so it is a tag prefix match unless the name matches
+func mongoEmoteScore(text string) bson.M {
+	index := bson.M{"$indexOfCP": bson.A{"$name_lower", text}}
+
+	return bson.M{"$switch": bson.M{
+		"branches": bson.A{
+			bson.M{"case": bson.M{"$eq": bson.A{"$name_lower", text}}, "then": ScoreExactName},
+			bson.M{"case": bson.M{"$eq": bson.A{index, 0}}, "then": ScorePrefixName},
+			bson.M{"case": bson.M{"$gt": bson.A{index, 0}}, "then": ScoreContainName},
+			bson.M{"case": bson.M{"$regexMatch": bson.M{"input": "$name_lower", "regex": fuzzyPattern(text)}}, "then": ScoreFuzzyName},
+			bson.M{"case": bson.M{"$in": bson.A{text, bson.M{"$ifNull": bson.A{"$tags", bson.A{}}}}}, "then": ScoreExactTag},
+		},
+		"default": ScorePrefixTag,
+	}}
+}
+
+// Get a pattern matching every character of the text in order, with anything in between
+func fuzzyPattern(text string) string {
+	chars := make([]string, 0, len(text))
+	for _, r := range text {
+		chars = append(chars, regexp.QuoteMeta(string(r)))
+	}
+
+	return strings.Join(chars, ".*")
+}
@@ -0,0 +1,289 @@
+//go:build mongo
+// +build mongo
+
+// These tests need a mongo server, configured the same way as the app, and are run with
+// go test -tags mongo -bench . ./src/search
+// The emotes are seeded in a separate database, named after the configured one
+package search
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// How many emotes are seeded, enough for the planner to prefer the indexes
+const seededEmotes = 20000
+
+var seedTags = []string{"pepe", "cat", "dance", "happy", "sad", "meme", "anime", "peepo", "kekw", "pog"}
+
+func TestMain(m *testing.M) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*2)
+	defer cancel()
+
+	mongo.Database = mongo.Database.Client().Database(configure.Config.GetString("mongo_db") + "_search_test")
+	if err := seed(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "could not seed emotes, err=%v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	_ = mongo.Database.Drop(context.Background())
+	os.Exit(code)
+}
+
+func seed(ctx context.Context) error {
+	if err := mongo.Database.Drop(ctx); err != nil {
+		return err
+	}
+	if _, err := mongo.Database.Collection("emotes").Indexes().CreateMany(ctx, mongo.EmoteIndexes); err != nil {
+		return err
+	}
+
+	r := rand.New(rand.NewSource(1))
+	emotes := make([]interface{}, seededEmotes)
+	for i := range emotes {
+		name := fmt.Sprintf("%s%s%d", strings.Title(seedTags[r.Intn(len(seedTags))]), strings.Title(seedTags[r.Intn(len(seedTags))]), i)
+		count := int32(r.Intn(10000))
+		status := int32(datastructure.EmoteStatusLive)
+		if i%10 == 0 {
+			status = datastructure.EmoteStatusDeleted
+		}
+
+		emote := bson.M{
+			"_id":           primitive.NewObjectID(),
+			"name":          name,
+			"name_lower":    strings.ToLower(name),
+			"owner":         primitive.NewObjectID(),
+			"status":        status,
+			"visibility":    int32(0),
+			"tags":          []string{seedTags[r.Intn(len(seedTags))], seedTags[r.Intn(len(seedTags))]},
+			"channel_count": count,
+		}
+		if i%4 == 0 {
+			emote["trending"] = bson.M{"week": int32(r.Intn(200) - 100)}
+		}
+		emotes[i] = emote
+	}
+
+	_, err := mongo.Database.Collection("emotes").InsertMany(ctx, emotes)
+	return err
+}
+
+// The search modes of the emote search, as the resolver builds them
+var searchModes = []struct {
+	name string
+	text string
+	sort bson.D
+}{
+	{"Age", "", bson.D{{Key: "_id", Value: -1}}},
+	{"Popularity", "", bson.D{{Key: "channel_count", Value: -1}}},
+	{"Trending", "", bson.D{{Key: "trending.week", Value: -1}, {Key: "channel_count", Value: -1}}},
+	{"TextRelevance", "pepe", nil},
+	{"TextPopularity", "pepe", bson.D{{Key: "channel_count", Value: -1}}},
+	{"TextAge", "peepo", bson.D{{Key: "_id", Value: -1}}},
+}
+
+func searchQuery(text string, sort bson.D) EmoteQuery {
+	return EmoteQuery{
+		Text:   text,
+		Filter: bson.M{"status": bson.M{"$in": datastructure.EmoteStatusesUsable}},
+		Sort:   sort,
+		Limit:  50,
+	}
+}
+
+// Get the query and its cursor for the second page
+func secondPage(t testing.TB, q EmoteQuery) EmoteQuery {
+	hits, err := Emotes.SearchEmotes(context.Background(), q)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) == 0 {
+		t.Fatal("no emotes found")
+	}
+
+	q.After = hits[len(hits)-1].Cursor
+	return q
+}
+
+func TestSearchEmotesUsesIndexes(t *testing.T) {
+	ctx := context.Background()
+	for _, mode := range searchModes {
+		for _, page := range []string{"First", "Second"} {
+			t.Run(mode.name+page, func(t *testing.T) {
+				q := searchQuery(mode.text, mode.sort)
+				if page == "Second" {
+					q = secondPage(t, q)
+				}
+
+				text := strings.ToLower(q.Text)
+				sort := mongoEmoteSort(q, text)
+				pipeline, err := mongoEmotePipeline(q, text, sort)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				explained := bson.M{}
+				err = mongo.Database.RunCommand(ctx, bson.D{
+					{Key: "explain", Value: bson.D{
+						{Key: "aggregate", Value: "emotes"},
+						{Key: "pipeline", Value: pipeline},
+						{Key: "cursor", Value: bson.M{}},
+					}},
+					{Key: "verbosity", Value: "queryPlanner"},
+				}).Decode(&explained)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				plans := winningPlans(explained)
+				if len(plans) == 0 {
+					t.Fatalf("no winning plan in %v", explained)
+				}
+				for _, plan := range plans {
+					b, err := bson.MarshalExtJSON(plan, false, false)
+					if err != nil {
+						t.Fatal(err)
+					}
+					s := string(b)
+
+					if !strings.Contains(s, `"stage":"IXSCAN"`) || strings.Contains(s, `"stage":"COLLSCAN"`) {
+						t.Errorf("the emotes are not matched with an index: %s", s)
+					}
+					// Without a text, every sort mode has an index providing its order
+					if mode.text == "" && strings.Contains(s, `"stage":"SORT"`) {
+						t.Errorf("the emotes are sorted in memory: %s", s)
+					}
+				}
+				if mode.text == "" && hasStage(explained, "$sort") {
+					t.Errorf("the emotes are sorted in the aggregation: %v", explained["stages"])
+				}
+			})
+		}
+	}
+}
+
+func TestSearchEmotesRanksMatches(t *testing.T) {
+	ctx := context.Background()
+
+	// The text is in none of the seeded names or tags, so only these emotes match it
+	expected := []struct {
+		name  string
+		tags  []string
+		score float64
+	}{
+		{"Zorb", nil, ScoreExactName},
+		{"ZorbDance", nil, ScorePrefixName},
+		{"FeelsZorb", nil, ScoreContainName},
+		{"ZxOxRxB", nil, ScoreFuzzyName},
+		{"Unrelated", []string{"zorb"}, ScoreExactTag},
+		{"Other", []string{"zorbing"}, ScorePrefixTag},
+	}
+	ids := bson.A{}
+	for _, e := range expected {
+		id := primitive.NewObjectID()
+		ids = append(ids, id)
+		if _, err := mongo.Database.Collection("emotes").InsertOne(ctx, bson.M{
+			"_id":           id,
+			"name":          e.name,
+			"name_lower":    strings.ToLower(e.name),
+			"status":        int32(datastructure.EmoteStatusLive),
+			"visibility":    int32(0),
+			"tags":          e.tags,
+			"channel_count": int32(0),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() {
+		_, _ = mongo.Database.Collection("emotes").DeleteMany(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	}()
+
+	hits, err := Emotes.SearchEmotes(ctx, searchQuery("zorb", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != len(expected) {
+		t.Fatalf("expected %d emotes, got %d", len(expected), len(hits))
+	}
+	for i, e := range expected {
+		hit := hits[i].Emote
+		if hit.Name != e.name || hit.SearchScore == nil || *hit.SearchScore != e.score {
+			t.Errorf("expected %s with score %v at %d, got %s with score %v", e.name, e.score, i, hit.Name, hit.SearchScore)
+		}
+	}
+}
+
+func BenchmarkSearchEmotes(b *testing.B) {
+	ctx := context.Background()
+	for _, mode := range searchModes {
+		for _, page := range []string{"First", "Second"} {
+			b.Run(mode.name+page, func(b *testing.B) {
+				q := searchQuery(mode.text, mode.sort)
+				if page == "Second" {
+					q = secondPage(b, q)
+				}
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if _, err := Emotes.SearchEmotes(ctx, q); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
+
+// Find the winning plans in the output of explain, wherever the server version puts them
+func winningPlans(v interface{}) []interface{} {
+	plans := []interface{}{}
+	switch v := v.(type) {
+	case bson.M:
+		for k, e := range v {
+			if k == "winningPlan" {
+				plans = append(plans, e)
+				continue
+			}
+			plans = append(plans, winningPlans(e)...)
+		}
+	case bson.D:
+		for _, e := range v {
+			if e.Key == "winningPlan" {
+				plans = append(plans, e.Value)
+				continue
+			}
+			plans = append(plans, winningPlans(e.Value)...)
+		}
+	case bson.A:
+		for _, e := range v {
+			plans = append(plans, winningPlans(e)...)
+		}
+	}
+
+	return plans
+}
+
+// Whether the aggregation runs a stage itself, rather than in the query it starts with
+func hasStage(explained bson.M, name string) bool {
+	stages, _ := explained["stages"].(bson.A)
+	for _, s := range stages {
+		b, err := bson.Marshal(s)
+		if err == nil && bson.Raw(b).Lookup(name).Type != 0 {
+			return true
+		}
+	}
+
+	return false
+}
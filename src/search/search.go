@@ -0,0 +1,52 @@
+package search
+
+import (
+	"context"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// A backend able to search emotes by text
+type EmoteBackend interface {
+	// Get a page of the emotes matching the query
+	SearchEmotes(ctx context.Context, q EmoteQuery) ([]*EmoteHit, error)
+	// Get the total amount of emotes matching the query
+	CountEmotes(ctx context.Context, q EmoteQuery) (int64, error)
+}
+
+// A search for emotes
+type EmoteQuery struct {
+	// The text the names of emotes must contain, in order, or their tags must start with, ignoring case. No text matches every emote
+	Text string
+	// Constraints every result must satisfy, such as status and visibility
+	Filter bson.M
+	// The order of the results, when empty and a text is specified, the results are ordered by relevance
+	Sort bson.D
+
+	// An opaque cursor returned with a previous result, only results after it are returned
+	After string
+
+	Skip  int64
+	Limit int64
+}
+
+// An emote matching a search
+type EmoteHit struct {
+	Emote *datastructure.Emote
+	// An opaque cursor pointing after this emote, for the same query
+	Cursor string
+}
+
+// Relevance scores of the ways an emote can match a search, a higher score ranks first
+const (
+	ScoreExactName   float64 = 100
+	ScorePrefixName  float64 = 75
+	ScoreContainName float64 = 50
+	ScoreFuzzyName   float64 = 25
+	ScoreExactTag    float64 = 10
+	ScorePrefixTag   float64 = 5
+)
+
+// The backend used to search emotes
+var Emotes EmoteBackend = &MongoBackend{}
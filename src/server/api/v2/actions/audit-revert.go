@@ -0,0 +1,506 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/discord"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The audit log types which can be reverted
+// Reverts can themselves be reverted, which redoes the original change
+var RevertibleAuditLogTypes = []int32{
+	datastructure.AuditLogTypeEmoteEdit,
+	datastructure.AuditLogTypeUserChannelEmoteAdd,
+	datastructure.AuditLogTypeUserChannelEmoteRemove,
+	datastructure.AuditLogTypeUserChannelEmoteImport,
+	datastructure.AuditLogTypeUserChannelEmoteSet,
+	datastructure.AuditLogTypeAuditRevert,
+}
+
+// The most entries reverted by a single bulk revert
+const maxBulkRevert = 500
+
+type AuditRevertResult struct {
+	Entry     *datastructure.AuditLog
+	RevertID  *primitive.ObjectID    // The audit log entry recording the revert, nil if nothing had to be changed
+	Applied   []string               // The keys which were reverted
+	Conflicts []*AuditRevertConflict // The keys which could not be reverted
+}
+
+type AuditRevertConflict struct {
+	Key    string
+	Reason string
+}
+
+func (r *AuditRevertResult) conflict(key string, reason string) {
+	r.Conflicts = append(r.Conflicts, &AuditRevertConflict{Key: key, Reason: reason})
+}
+
+// A bulk revert of everything a user has done
+// Later changes by that user and the reverts written by the bulk revert itself are being undone too, so they aren't conflicts
+type bulkRevert struct {
+	userID  primitive.ObjectID
+	reverts []primitive.ObjectID
+}
+
+// Undo the changes of an audit log entry
+// Changes which have been modified again since are skipped and reported as conflicts
+func RevertAuditEntry(ctx context.Context, actor *datastructure.User, entryID primitive.ObjectID, reason *string) (*AuditRevertResult, error) {
+	entry := &datastructure.AuditLog{}
+	if err := mongo.Database.Collection("audit").FindOne(ctx, bson.M{"_id": entryID}).Decode(entry); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownAuditEntry
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return revertAuditEntry(ctx, actor, entry, reason, nil)
+}
+
+// Undo everything a user has done since a point in time, newest first
+// Entries the actor isn't allowed to revert are left out
+func RevertUserActions(ctx context.Context, actor *datastructure.User, userID primitive.ObjectID, since time.Time, reason *string) ([]*AuditRevertResult, error) {
+	entries := []*datastructure.AuditLog{}
+	cur, err := mongo.Database.Collection("audit").Find(ctx, audit.Filter{
+		Types:   RevertibleAuditLogTypes,
+		ActorID: &userID,
+		Since:   &since,
+	}.Match(), options.Find().SetSort(bson.M{"_id": -1}).SetLimit(maxBulkRevert))
+	if err == nil {
+		err = cur.All(ctx, &entries)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	bulk := &bulkRevert{userID: userID, reverts: []primitive.ObjectID{}}
+	results := []*AuditRevertResult{}
+	for _, entry := range entries {
+		result, err := revertAuditEntry(ctx, actor, entry, reason, bulk)
+		switch err {
+		case nil:
+			if result.RevertID != nil {
+				bulk.reverts = append(bulk.reverts, *result.RevertID)
+			}
+			results = append(results, result)
+		case resolvers.ErrAccessDenied, resolvers.ErrNotRevertible:
+			continue
+		case resolvers.ErrAlreadyReverted, resolvers.ErrUnknownEmote, resolvers.ErrUnknownChannel, resolvers.ErrUserBanned:
+			result = &AuditRevertResult{Entry: entry}
+			result.conflict("", err.Error())
+			results = append(results, result)
+		default:
+			return nil, err
+		}
+	}
+
+	return results, nil
+}
+
+func revertAuditEntry(ctx context.Context, actor *datastructure.User, entry *datastructure.AuditLog, reason *string, bulk *bulkRevert) (*AuditRevertResult, error) {
+	if !revertible(entry) {
+		return nil, resolvers.ErrNotRevertible
+	}
+
+	if err := mongo.Database.Collection("audit").FindOne(ctx, bson.M{"reverts": entry.ID}).Err(); err == nil {
+		return nil, resolvers.ErrAlreadyReverted
+	} else if err != mongo.ErrNoDocuments {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	if reason == nil {
+		r := fmt.Sprintf("Revert of %s", entry.ID.Hex())
+		reason = &r
+	}
+
+	switch entry.Target.Type {
+	case "emotes":
+		return revertEmoteEdit(ctx, actor, entry, reason)
+	case "users":
+		return revertChannelEmotes(ctx, actor, entry, reason, bulk)
+	}
+
+	return nil, resolvers.ErrNotRevertible
+}
+
+func revertible(entry *datastructure.AuditLog) bool {
+	if entry.Target == nil || entry.Target.ID == nil || len(entry.Changes) == 0 {
+		return false
+	}
+
+	for _, t := range RevertibleAuditLogTypes {
+		if entry.Type == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func revertEmoteEdit(ctx context.Context, actor *datastructure.User, entry *datastructure.AuditLog, reason *string) (*AuditRevertResult, error) {
+	emote := &datastructure.Emote{}
+	if err := mongo.Database.Collection("emotes").FindOne(ctx, bson.M{
+		"_id":    entry.Target.ID,
+		"status": bson.M{"$ne": datastructure.EmoteStatusDeleted},
+	}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	if err := authorization.Authorize(ctx, actor, authorization.EmoteEdit, emote); err != nil {
+		return nil, err
+	}
+
+	result := &AuditRevertResult{Entry: entry}
+
+	// Only revert the keys which still hold the value the entry set,
+	// and require them to still hold it while updating
+	filter := bson.M{"_id": emote.ID}
+	update := bson.M{}
+	changes := []*datastructure.AuditLogChange{}
+	for _, c := range entry.Changes {
+		var current, newValue, oldValue interface{}
+		var ok bool
+		switch c.Key {
+		case "name":
+			current = emote.Name
+			newValue, ok = auditString(c.NewValue)
+			if ok {
+				oldValue, ok = auditString(c.OldValue)
+			}
+		case "owner":
+			current = emote.OwnerID
+			newValue, ok = auditObjectID(c.NewValue)
+			if ok {
+				oldValue, ok = auditObjectID(c.OldValue)
+			}
+		case "tags":
+			current = emote.Tags
+			newValue, ok = auditStrings(c.NewValue)
+			if ok {
+				oldValue, ok = auditStrings(c.OldValue)
+			}
+		case "visibility":
+			current = emote.Visibility
+			newValue, ok = auditInt32(c.NewValue)
+			if ok {
+				oldValue, ok = auditInt32(c.OldValue)
+			}
+		}
+		if !ok {
+			result.conflict(c.Key, "Not Revertible")
+			continue
+		}
+
+		if !auditValueEqual(current, newValue) {
+			if !auditValueEqual(current, oldValue) {
+				result.conflict(c.Key, "Changed Since")
+			}
+			continue
+		}
+
+		if c.Key == "visibility" && !authorization.Can(ctx, actor, authorization.EmoteModerate, nil) {
+			old := int64(oldValue.(int32))
+			if utils.BitField.HasBits(old, int64(datastructure.EmoteVisibilityGlobal)) != utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityGlobal)) ||
+				(utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityHidden)) && !utils.BitField.HasBits(old, int64(datastructure.EmoteVisibilityHidden))) {
+				result.conflict(c.Key, resolvers.ErrAccessDenied.Error())
+				continue
+			}
+		}
+
+		if c.Key == "visibility" && utils.BitField.HasBits(int64(oldValue.(int32)), int64(datastructure.EmoteVisibilityGlobal)) != utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityGlobal)) {
+			// The global emote sets decide which emotes are global while they exist
+			exist, err := GlobalEmoteSetsExist(ctx)
+			if err != nil {
+				log.Errorf("mongo, err=%v", err)
+				return nil, resolvers.ErrInternalServer
+			}
+			if exist {
+				result.conflict(c.Key, resolvers.ErrGlobalEmoteSetRequired.Error())
+				continue
+			}
+		}
+
+		filter[c.Key] = newValue
+		if tags, ok := newValue.([]string); ok {
+			if len(tags) == 0 {
+				// Emotes without tags may store them as null
+				filter[c.Key] = bson.M{"$in": bson.A{nil, bson.A{}}}
+			} else {
+				filter[c.Key] = bson.M{"$all": tags, "$size": len(tags)}
+			}
+		}
+		update[c.Key] = oldValue
+		if c.Key == "name" {
+			update["name_lower"] = strings.ToLower(oldValue.(string))
+		}
+		changes = append(changes, &datastructure.AuditLogChange{Key: c.Key, OldValue: current, NewValue: oldValue})
+		result.Applied = append(result.Applied, c.Key)
+	}
+
+	if len(update) == 0 {
+		return result, nil
+	}
+
+	update["last_modified_date"] = time.Now()
+	after := options.After
+	if err := mongo.Database.Collection("emotes").FindOneAndUpdate(ctx, filter, bson.M{
+		"$set": update,
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			// The emote was modified while reverting
+			for _, key := range result.Applied {
+				result.conflict(key, "Changed Since")
+			}
+			result.Applied = nil
+			return result, nil
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	revert := &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeAuditRevert,
+		CreatedBy: actor.ID,
+		Target:    &datastructure.Target{ID: &emote.ID, Type: "emotes"},
+		Changes:   changes,
+		Reason:    reason,
+		Reverts:   &entry.ID,
+	}
+	if err := audit.Insert(ctx, revert); err != nil {
+		log.Errorf("mongo, err=%v", err)
+	} else {
+		result.RevertID = &revert.ID
+	}
+
+	go discord.SendEmoteEdit(*emote, *actor, changes, reason)
+	return result, nil
+}
+
+func revertChannelEmotes(ctx context.Context, actor *datastructure.User, entry *datastructure.AuditLog, reason *string, bulk *bulkRevert) (*AuditRevertResult, error) {
+	channel, err := GetChannel(ctx, *entry.Target.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorization.Authorize(ctx, actor, authorization.ChannelEmotesSet, channel); err != nil {
+		return nil, err
+	}
+
+	result := &AuditRevertResult{Entry: entry}
+
+	var added, removed []primitive.ObjectID
+	for _, c := range entry.Changes {
+		oldIDs, ok := audit.ObjectIDs(c.OldValue)
+		newIDs, ok2 := audit.ObjectIDs(c.NewValue)
+		if c.Key != "emotes" || !ok || !ok2 {
+			result.conflict(c.Key, "Not Revertible")
+			continue
+		}
+		added, removed = DiffEmoteIDs(oldIDs, newIDs)
+	}
+
+	// Emotes which were added or removed again since can't be reverted without overwriting that change
+	changedSince, err := channelEmotesChangedSince(ctx, channel.ID, entry.ID, bulk)
+	if err != nil {
+		return nil, err
+	}
+
+	undoAdd := make(map[primitive.ObjectID]bool, len(added))
+	for _, id := range added {
+		if changedSince[id] {
+			result.conflict(fmt.Sprintf("emotes.%s", id.Hex()), "Changed Since")
+			continue
+		}
+		undoAdd[id] = true
+	}
+
+	emoteIDs := []primitive.ObjectID{}
+	has := make(map[primitive.ObjectID]bool, len(channel.EmoteIDs))
+	for _, id := range channel.EmoteIDs {
+		if undoAdd[id] {
+			continue
+		}
+		has[id] = true
+		emoteIDs = append(emoteIDs, id)
+	}
+
+	// Emotes which were removed are added back if they are still available and there is room
+	for _, id := range removed {
+		key := fmt.Sprintf("emotes.%s", id.Hex())
+		if changedSince[id] {
+			result.conflict(key, "Changed Since")
+			continue
+		}
+		if has[id] {
+			continue
+		}
+
+		if _, err := GetAddableEmote(ctx, channel, id); err != nil {
+			if err == resolvers.ErrInternalServer {
+				return nil, err
+			}
+			result.conflict(key, err.Error())
+			continue
+		}
+		if err := CheckChannelEmoteSlots(actor, channel, len(emoteIDs)+1); err != nil {
+			result.conflict(key, err.Error())
+			continue
+		}
+
+		has[id] = true
+		emoteIDs = append(emoteIDs, id)
+	}
+
+	if len(emoteIDs) == len(channel.EmoteIDs) && !utils.DifferentArray(hexIDs(emoteIDs), hexIDs(channel.EmoteIDs)) {
+		return result, nil
+	}
+
+	revert := &datastructure.AuditLog{
+		Type:    datastructure.AuditLogTypeAuditRevert,
+		Reason:  reason,
+		Reverts: &entry.ID,
+	}
+	// The emotes are only set if they weren't changed while reverting
+	if _, err := setChannelEmotes(ctx, actor, channel, emoteIDs, revert, true); err != nil {
+		if err == errChannelEmotesChanged {
+			undone, redone := DiffEmoteIDs(channel.EmoteIDs, emoteIDs)
+			for _, id := range append(undone, redone...) {
+				result.conflict(fmt.Sprintf("emotes.%s", id.Hex()), "Changed Since")
+			}
+			return result, nil
+		}
+		return nil, err
+	}
+	if !revert.ID.IsZero() {
+		result.RevertID = &revert.ID
+	}
+	result.Applied = append(result.Applied, "emotes")
+
+	return result, nil
+}
+
+// Get the emotes which were added to or removed from a channel after an audit log entry
+// During a bulk revert, the changes it is undoing or has made itself are left out
+func channelEmotesChangedSince(ctx context.Context, channelID primitive.ObjectID, entryID primitive.ObjectID, bulk *bulkRevert) (map[primitive.ObjectID]bool, error) {
+	filter := bson.M{
+		"_id":         bson.M{"$gt": entryID},
+		"target.id":   channelID,
+		"target.type": "users",
+		"changes.key": "emotes",
+	}
+	if bulk != nil {
+		filter["_id"] = bson.M{"$gt": entryID, "$nin": bulk.reverts}
+		filter["action_user"] = bson.M{"$ne": bulk.userID}
+	}
+
+	entries := []*datastructure.AuditLog{}
+	cur, err := mongo.Database.Collection("audit").Find(ctx, filter, options.Find().SetProjection(bson.M{"changes": 1}))
+	if err == nil {
+		err = cur.All(ctx, &entries)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	changed := map[primitive.ObjectID]bool{}
+	for _, e := range entries {
+		for _, c := range e.Changes {
+			oldIDs, ok := audit.ObjectIDs(c.OldValue)
+			newIDs, ok2 := audit.ObjectIDs(c.NewValue)
+			if c.Key != "emotes" || !ok || !ok2 {
+				continue
+			}
+
+			added, removed := DiffEmoteIDs(oldIDs, newIDs)
+			for _, id := range append(added, removed...) {
+				changed[id] = true
+			}
+		}
+	}
+
+	return changed, nil
+}
+
+func hexIDs(ids []primitive.ObjectID) []string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = id.Hex()
+	}
+
+	return s
+}
+
+// Helpers reading the values of audit log changes, which come back from the database as generic types
+
+func auditString(v interface{}) (string, bool) {
+	s, ok := v.(string)
+	return s, ok
+}
+
+func auditObjectID(v interface{}) (primitive.ObjectID, bool) {
+	id, ok := v.(primitive.ObjectID)
+	return id, ok
+}
+
+func auditInt32(v interface{}) (int32, bool) {
+	switch v := v.(type) {
+	case int32:
+		return v, true
+	case int64:
+		return int32(v), true
+	case float64:
+		return int32(v), true
+	}
+
+	return 0, false
+}
+
+func auditStrings(v interface{}) ([]string, bool) {
+	a, ok := v.(primitive.A)
+	if v == nil {
+		return []string{}, true
+	}
+	if !ok {
+		return nil, false
+	}
+
+	s := make([]string, len(a))
+	for i, e := range a {
+		if s[i], ok = e.(string); !ok {
+			return nil, false
+		}
+	}
+
+	return s, true
+}
+
+func auditValueEqual(a interface{}, b interface{}) bool {
+	if sa, ok := a.([]string); ok {
+		sb, ok := b.([]string)
+		return ok && !utils.DifferentArray(sa, sb)
+	}
+
+	return a == b
+}
@@ -0,0 +1,97 @@
+//go:build mongo
+// +build mongo
+
+// These tests need a mongo and a redis server, configured the same way as the app, and are run with
+// go test -tags mongo ./src/server/api/v2/actions
+// The documents are written to a separate database, named after the configured one
+package actions
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	authorization_store "github.com/SevenTV/ServerGo/src/authorization/store"
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMain(m *testing.M) {
+	mongo.Database = mongo.Database.Client().Database(configure.Config.GetString("mongo_db") + "_actions_test")
+	_ = mongo.Database.Drop(context.Background())
+	authorization.SetStore(authorization_store.Database{})
+
+	code := m.Run()
+	_ = mongo.Database.Drop(context.Background())
+	os.Exit(code)
+}
+
+// Reverting everything a user did undoes an emote they added and removed again, rather than conflicting with their removal
+func TestRevertUserActionsAddThenRemove(t *testing.T) {
+	ctx := context.Background()
+	since := time.Now().Add(-time.Second)
+
+	owner := &datastructure.User{
+		ID:       primitive.NewObjectID(),
+		Login:    "owner",
+		EmoteIDs: []primitive.ObjectID{},
+		Role:     &datastructure.Role{Allowed: datastructure.RolePermissionDefault},
+	}
+	attacker := &datastructure.User{
+		ID:    primitive.NewObjectID(),
+		Login: "attacker",
+	}
+	if _, err := mongo.Database.Collection("users").InsertOne(ctx, owner); err != nil {
+		t.Fatal(err)
+	}
+
+	emoteID := primitive.NewObjectID()
+	if _, err := mongo.Database.Collection("emotes").InsertOne(ctx, bson.M{
+		"_id":        emoteID,
+		"name":       "Zorb",
+		"name_lower": "zorb",
+		"owner":      primitive.NewObjectID(),
+		"status":     int32(datastructure.EmoteStatusLive),
+		"visibility": int32(0),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	channel, err := setChannelEmotes(ctx, attacker, owner, []primitive.ObjectID{emoteID}, &datastructure.AuditLog{
+		Type: datastructure.AuditLogTypeUserChannelEmoteAdd,
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := setChannelEmotes(ctx, attacker, channel, []primitive.ObjectID{}, &datastructure.AuditLog{
+		Type: datastructure.AuditLogTypeUserChannelEmoteRemove,
+	}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := RevertUserActions(ctx, owner, attacker.ID, since, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 reverted entries, got %d", len(results))
+	}
+	for _, r := range results {
+		for _, c := range r.Conflicts {
+			t.Errorf("unexpected conflict reverting %s: %s %s", r.Entry.ID.Hex(), c.Key, c.Reason)
+		}
+	}
+
+	channel, err = GetChannel(ctx, owner.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(channel.EmoteIDs) != 0 {
+		t.Errorf("expected the channel to have no emotes, got %v", channel.EmoteIDs)
+	}
+}
@@ -0,0 +1,277 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// How often the scheduler looks for due channel emote schedules
+const channelEmoteSchedulerInterval = time.Second * 15
+
+// How long a node may hold a claimed schedule before another node is allowed to take it over
+const channelEmoteScheduleLease = time.Minute * 5
+
+// Start the worker executing due channel emote schedules
+//
+// Every node runs this worker. A schedule is claimed atomically before it is executed,
+// so only a single node carries it out. Should a node die while holding a claim,
+// the schedule is picked up again once the lease has run out
+func StartChannelEmoteScheduler(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(channelEmoteSchedulerInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				runDueChannelEmoteSchedules(ctx)
+			}
+		}
+	}()
+}
+
+// Claim and execute due schedules until there are none left
+func runDueChannelEmoteSchedules(ctx context.Context) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("recovered, err=%v", err)
+		}
+	}()
+
+	for {
+		schedule, err := claimChannelEmoteSchedule(ctx)
+		if err != nil {
+			if err != mongo.ErrNoDocuments {
+				log.Errorf("mongo, err=%v", err)
+			}
+			return
+		}
+
+		status := datastructure.ChannelEmoteScheduleStatusDone
+		var errMsg *string
+		if err := ExecuteChannelEmoteSchedule(ctx, schedule); err == errChannelEmoteScheduleClaimLost {
+			log.WithField("schedule", schedule.ID.Hex()).Warn("channel emote schedule was taken over by another node")
+			continue
+		} else if err != nil {
+			status = datastructure.ChannelEmoteScheduleStatusFailed
+			errMsg = utils.StringPointer(err.Error())
+			log.WithField("schedule", schedule.ID.Hex()).Warnf("channel emote schedule failed, err=%v", err)
+		}
+
+		// Only finish the schedule if this node still holds the claim
+		_, err = mongo.Database.Collection("channel_emote_schedules").UpdateOne(ctx, bson.M{
+			"_id":        schedule.ID,
+			"status":     datastructure.ChannelEmoteScheduleStatusRunning,
+			"claimed_by": schedule.ClaimedBy,
+		}, bson.M{
+			"$set": bson.M{
+				"status":      status,
+				"executed_at": time.Now(),
+				"error":       errMsg,
+			},
+		})
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+		}
+	}
+}
+
+func claimChannelEmoteSchedule(ctx context.Context) (*datastructure.ChannelEmoteSchedule, error) {
+	now := time.Now()
+	claimedBy := fmt.Sprintf("%s:%s", configure.PodName, primitive.NewObjectID().Hex())
+
+	after := options.After
+	doc := mongo.Database.Collection("channel_emote_schedules").FindOneAndUpdate(ctx, bson.M{
+		"execute_at": bson.M{"$lte": now},
+		"$or": bson.A{
+			bson.M{"status": datastructure.ChannelEmoteScheduleStatusPending},
+			bson.M{
+				"status":     datastructure.ChannelEmoteScheduleStatusRunning,
+				"claimed_at": bson.M{"$lt": now.Add(-channelEmoteScheduleLease)},
+			},
+		},
+	}, bson.M{
+		"$set": bson.M{
+			"status":     datastructure.ChannelEmoteScheduleStatusRunning,
+			"claimed_by": claimedBy,
+			"claimed_at": now,
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+		Sort:           bson.M{"execute_at": 1},
+	})
+
+	schedule := &datastructure.ChannelEmoteSchedule{}
+	if err := doc.Decode(schedule); err != nil {
+		return nil, err
+	}
+
+	return schedule, nil
+}
+
+// The node executing a schedule no longer holds its claim, because its lease ran out and another node took it over
+var errChannelEmoteScheduleClaimLost = fmt.Errorf("channel emote schedule claim lost")
+
+// How many times applying a schedule is attempted when the channel's emotes change while applying it
+const channelEmoteScheduleAttempts = 3
+
+// Apply a channel emote schedule on behalf of the user who created it
+//
+// The actor's access and the emotes are checked again, as either may have changed since the schedule was created.
+// Applying a schedule is idempotent, it brings the channel's current emotes in line with the action.
+// The claim is renewed right before writing and the emotes are only written if they are still the ones read,
+// so a node whose lease ran out doesn't write after another node took the schedule over
+func ExecuteChannelEmoteSchedule(ctx context.Context, schedule *datastructure.ChannelEmoteSchedule) error {
+	actor := &datastructure.User{}
+	if err := mongo.Database.Collection("users").FindOne(ctx, bson.M{"_id": schedule.CreatedByID}).Decode(actor); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return resolvers.ErrUnknownUser
+		}
+		log.Errorf("mongo, err=%v", err)
+		return resolvers.ErrInternalServer
+	}
+	role := datastructure.GetRole(ctx, actor.RoleID)
+	actor.Role = &role
+
+	for attempt := 1; ; attempt++ {
+		channel, err := GetChannel(ctx, schedule.ChannelID)
+		if err != nil {
+			return err
+		}
+
+		if err := authorization.Authorize(ctx, actor, ChannelEmoteScheduleAuthorization(schedule.Action), channel); err != nil {
+			return err
+		}
+
+		emoteIDs, auditType, err := ApplyChannelEmoteSchedule(ctx, actor, channel, schedule.Action, schedule.EmoteIDs)
+		if err != nil {
+			return err
+		}
+
+		if err := renewChannelEmoteScheduleClaim(ctx, schedule); err != nil {
+			return err
+		}
+		// Already applied, such as by a node which lost its claim after writing
+		if len(emoteIDs) == len(channel.EmoteIDs) && !utils.DifferentArray(hexIDs(emoteIDs), hexIDs(channel.EmoteIDs)) {
+			return nil
+		}
+
+		_, err = setChannelEmotes(ctx, actor, channel, emoteIDs, &datastructure.AuditLog{
+			Type:   auditType,
+			Reason: schedule.Reason,
+		}, true)
+		if err != errChannelEmotesChanged || attempt == channelEmoteScheduleAttempts {
+			return err
+		}
+	}
+}
+
+// Extend the lease of a claimed schedule, failing if this node no longer holds the claim
+func renewChannelEmoteScheduleClaim(ctx context.Context, schedule *datastructure.ChannelEmoteSchedule) error {
+	res, err := mongo.Database.Collection("channel_emote_schedules").UpdateOne(ctx, bson.M{
+		"_id":        schedule.ID,
+		"status":     datastructure.ChannelEmoteScheduleStatusRunning,
+		"claimed_by": schedule.ClaimedBy,
+	}, bson.M{
+		"$set": bson.M{
+			"claimed_at": time.Now(),
+		},
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return resolvers.ErrInternalServer
+	}
+	if res.MatchedCount == 0 {
+		return errChannelEmoteScheduleClaimLost
+	}
+
+	return nil
+}
+
+// Get the authorization action needed to schedule a channel emote action
+func ChannelEmoteScheduleAuthorization(action string) authorization.Action {
+	switch action {
+	case datastructure.ChannelEmoteScheduleActionAdd:
+		return authorization.ChannelEmotesAdd
+	case datastructure.ChannelEmoteScheduleActionRemove:
+		return authorization.ChannelEmotesRemove
+	}
+
+	return authorization.ChannelEmotesSet
+}
+
+// Get the emote list a channel ends up with after applying a scheduled action
+// This also returns the audit log type matching the action
+func ApplyChannelEmoteSchedule(ctx context.Context, actor *datastructure.User, channel *datastructure.User, action string, emoteIDs []primitive.ObjectID) ([]primitive.ObjectID, int32, error) {
+	newIDs := []primitive.ObjectID{}
+	var auditType int32
+
+	switch action {
+	case datastructure.ChannelEmoteScheduleActionAdd:
+		auditType = datastructure.AuditLogTypeUserChannelEmoteAdd
+		newIDs = append(newIDs, channel.EmoteIDs...)
+		added, _ := DiffEmoteIDs(channel.EmoteIDs, emoteIDs)
+		for _, id := range added {
+			if _, err := GetAddableEmote(ctx, channel, id); err != nil {
+				return nil, 0, err
+			}
+			newIDs = append(newIDs, id)
+		}
+	case datastructure.ChannelEmoteScheduleActionRemove:
+		auditType = datastructure.AuditLogTypeUserChannelEmoteRemove
+		remove := make(map[primitive.ObjectID]bool, len(emoteIDs))
+		for _, id := range emoteIDs {
+			remove[id] = true
+		}
+		for _, id := range channel.EmoteIDs {
+			if !remove[id] {
+				newIDs = append(newIDs, id)
+			}
+		}
+	case datastructure.ChannelEmoteScheduleActionSet:
+		auditType = datastructure.AuditLogTypeUserChannelEmoteSet
+		current := make(map[primitive.ObjectID]bool, len(channel.EmoteIDs))
+		for _, id := range channel.EmoteIDs {
+			current[id] = true
+		}
+		seen := make(map[primitive.ObjectID]bool, len(emoteIDs))
+		for _, id := range emoteIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			if !current[id] {
+				if _, err := GetAddableEmote(ctx, channel, id); err != nil {
+					return nil, 0, err
+				}
+			}
+			newIDs = append(newIDs, id)
+		}
+	default:
+		return nil, 0, resolvers.ErrInvalidScheduleAction
+	}
+
+	// Channels above their limit may still shrink their emote list
+	if len(newIDs) > len(channel.EmoteIDs) {
+		if err := CheckChannelEmoteSlots(actor, channel, len(newIDs)); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return newIDs, auditType, nil
+}
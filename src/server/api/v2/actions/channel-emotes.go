@@ -0,0 +1,226 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Get a channel by its ID
+// This fails if the channel is unknown or banned
+func GetChannel(ctx context.Context, channelID primitive.ObjectID) (*datastructure.User, error) {
+	banned, err := authorization.IsBanned(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if banned {
+		return nil, resolvers.ErrUserBanned
+	}
+
+	res := mongo.Database.Collection("users").FindOne(ctx, bson.M{
+		"_id": channelID,
+	})
+
+	channel := &datastructure.User{}
+
+	err = res.Err()
+
+	if err == nil {
+		err = res.Decode(channel)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownChannel
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return channel, nil
+}
+
+// Verify that a channel has room for the specified amount of emotes
+func CheckChannelEmoteSlots(actor *datastructure.User, channel *datastructure.User, count int) error {
+	if actor.HasPermission(datastructure.RolePermissionManageUsers) {
+		return nil
+	}
+
+	maxEmoteSlots := channel.GetEmoteSlots()
+	if count > int(maxEmoteSlots) {
+		return resolvers.ErrEmoteSlotLimitReached(maxEmoteSlots)
+	}
+
+	return nil
+}
+
+// Get an emote which can be added to the specified channel
+func GetAddableEmote(ctx context.Context, channel *datastructure.User, emoteID primitive.ObjectID) (*datastructure.Emote, error) {
+	emoteRes := mongo.Database.Collection("emotes").FindOne(ctx, bson.M{
+		"_id":    emoteID,
+		"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
+	})
+
+	emote := &datastructure.Emote{}
+	err := emoteRes.Err()
+	if err == nil {
+		err = emoteRes.Decode(emote)
+	}
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	sharedWith := []string{}
+	for _, v := range emote.SharedWith {
+		sharedWith = append(sharedWith, v.Hex())
+	}
+
+	if utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityPrivate)) {
+		if emote.OwnerID.Hex() != channel.ID.Hex() && !utils.Contains(sharedWith, channel.ID.Hex()) {
+			return nil, resolvers.ErrUnknownEmote
+		}
+	}
+
+	return emote, nil
+}
+
+// Replace the emote list of a channel
+// This writes an audit log entry and notifies subscribers of every added or removed emote
+func SetChannelEmotes(ctx context.Context, actor *datastructure.User, channel *datastructure.User, emoteIDs []primitive.ObjectID, auditType int32, reason *string) (*datastructure.User, error) {
+	return setChannelEmotes(ctx, actor, channel, emoteIDs, &datastructure.AuditLog{
+		Type:   auditType,
+		Reason: reason,
+	}, false)
+}
+
+// The emote list of a channel was changed by someone else while being replaced
+var errChannelEmotesChanged = fmt.Errorf("channel emotes changed")
+
+// Replace the emote list of a channel, recording the change in the given audit log entry
+// With ifUnchanged, the list is only replaced if it still is the one read with the channel, otherwise errChannelEmotesChanged is returned
+func setChannelEmotes(ctx context.Context, actor *datastructure.User, channel *datastructure.User, emoteIDs []primitive.ObjectID, entry *datastructure.AuditLog, ifUnchanged bool) (*datastructure.User, error) {
+	oldIDs := channel.EmoteIDs
+
+	filter := bson.M{"_id": channel.ID}
+	if ifUnchanged {
+		filter["emotes"] = oldIDs
+		if len(oldIDs) == 0 {
+			// Channels without emotes may store them as null
+			filter["emotes"] = bson.M{"$in": bson.A{nil, bson.A{}}}
+		}
+	}
+
+	after := options.After
+	doc := mongo.Database.Collection("users").FindOneAndUpdate(ctx, filter, bson.M{
+		"$set": bson.M{
+			"emotes": emoteIDs,
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if ifUnchanged && doc.Err() == mongo.ErrNoDocuments {
+		return nil, errChannelEmotesChanged
+	}
+	if doc.Err() != nil {
+		log.Errorf("mongo, err=%v", doc.Err())
+		return nil, resolvers.ErrInternalServer
+	}
+
+	updated := &datastructure.User{}
+	if err := doc.Decode(updated); err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	entry.CreatedBy = actor.ID
+	entry.Target = &datastructure.Target{ID: &channel.ID, Type: "users"}
+	entry.Changes = []*datastructure.AuditLogChange{
+		{Key: "emotes", OldValue: oldIDs, NewValue: emoteIDs},
+	}
+	if err := audit.Insert(ctx, entry); err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	// Push events to redis
+	added, removed := DiffEmoteIDs(oldIDs, emoteIDs)
+	for _, id := range added {
+		publishChannelEmote(ctx, actor, updated, id, false)
+	}
+	for _, id := range removed {
+		publishChannelEmote(ctx, actor, updated, id, true)
+	}
+	recordChannelEmoteEvents(ctx, channel.ID, added, removed)
+
+	return updated, nil
+}
+
+// Get the IDs which were added and removed between two emote lists
+func DiffEmoteIDs(before []primitive.ObjectID, after []primitive.ObjectID) ([]primitive.ObjectID, []primitive.ObjectID) {
+	added := []primitive.ObjectID{}
+	removed := []primitive.ObjectID{}
+
+	had := make(map[primitive.ObjectID]bool, len(before))
+	for _, id := range before {
+		had[id] = true
+	}
+	has := make(map[primitive.ObjectID]bool, len(after))
+	for _, id := range after {
+		has[id] = true
+		if !had[id] {
+			added = append(added, id)
+		}
+	}
+	for _, id := range before {
+		if !has[id] {
+			removed = append(removed, id)
+		}
+	}
+
+	return added, removed
+}
+
+func publishChannelEmote(ctx context.Context, actor *datastructure.User, channel *datastructure.User, emoteID primitive.ObjectID, removed bool) {
+	if err := redis.Publish(ctx, fmt.Sprintf("users:%v:emotes", channel.Login), redis.PubSubPayloadUserEmotes{
+		Removed: removed,
+		ID:      emoteID.Hex(),
+		Actor:   actor.DisplayName,
+	}); err != nil {
+		log.Errorf("redis, err=%v", err)
+	}
+}
+
+// Publish an emote being added to or removed from every channel which has it enabled, as well as from the global emotes
+func PublishEmoteAvailability(ctx context.Context, actor *datastructure.User, emote *datastructure.Emote, removed bool) {
+	channels := []*datastructure.User{}
+	cur, err := mongo.Database.Collection("users").Find(ctx, bson.M{
+		"emotes": emote.ID,
+	}, options.Find().SetProjection(bson.M{"login": 1}))
+	if err == nil {
+		err = cur.All(ctx, &channels)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	for _, channel := range channels {
+		publishChannelEmote(ctx, actor, channel, emote.ID, removed)
+	}
+
+	if utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityGlobal)) {
+		publishGlobalEmote(ctx, emote.ID, removed)
+	}
+}
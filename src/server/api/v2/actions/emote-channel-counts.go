@@ -0,0 +1,103 @@
+package actions
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// How often a batch of channel counts is recomputed
+const emoteChannelCountInterval = time.Minute
+
+// How many emotes have their channel count recomputed per batch
+const emoteChannelCountBatchSize = 250
+
+// How long a channel count is considered up to date
+const emoteChannelCountMaxAge = time.Hour * 6
+
+// Start the worker recomputing the channel counts of emotes, the least recently checked emotes come first
+func StartEmoteChannelCountWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(emoteChannelCountInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := UpdateEmoteChannelCounts(ctx, emoteChannelCountBatchSize); err != nil {
+					log.Errorf("emote channel counts, err=%v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Recompute the channel counts of a batch of emotes which have not been checked recently
+//
+// Every node may run this at the same time: a count is only written if the emote was not checked by another node in the meantime,
+// so each check is recorded in the time series exactly once
+func UpdateEmoteChannelCounts(ctx context.Context, batchSize int64) error {
+	now := time.Now()
+
+	// Emotes which were never checked have no check date and are sorted first
+	emotes := []*datastructure.Emote{}
+	cur, err := mongo.Database.Collection("emotes").Find(ctx, bson.M{
+		"status": bson.M{"$ne": datastructure.EmoteStatusDeleted},
+		"$or": bson.A{
+			bson.M{"channel_count_checked_at": nil},
+			bson.M{"channel_count_checked_at": bson.M{"$lt": now.Add(-emoteChannelCountMaxAge)}},
+		},
+	}, options.Find().
+		SetSort(bson.M{"channel_count_checked_at": 1}).
+		SetLimit(batchSize).
+		SetProjection(bson.M{"_id": 1, "channel_count_checked_at": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	if err := cur.All(ctx, &emotes); err != nil {
+		return err
+	}
+
+	for _, e := range emotes {
+		count, err := mongo.Database.Collection("users").CountDocuments(ctx, bson.M{
+			"emotes": e.ID,
+		})
+		if err != nil {
+			return err
+		}
+
+		res, err := mongo.Database.Collection("emotes").UpdateOne(ctx, bson.M{
+			"_id":                      e.ID,
+			"channel_count_checked_at": e.LastChannelCountCheck,
+		}, bson.M{
+			"$set": bson.M{
+				"channel_count":            int32(count),
+				"channel_count_checked_at": now,
+			},
+		})
+		if err != nil {
+			return err
+		}
+		if res.ModifiedCount == 0 {
+			continue
+		}
+
+		if _, err := mongo.Database.Collection("emote_channel_counts").InsertOne(ctx, &datastructure.EmoteChannelCountSample{
+			EmoteID:   e.ID,
+			Count:     int32(count),
+			Timestamp: now,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package actions
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/bsm/redislock"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// How many emotes have their lowercased name set per batch
+const emoteNameBackfillBatchSize = 1000
+
+// How long the backfill holds its lock without finishing a batch
+const emoteNameBackfillLockDuration = time.Minute
+
+// Start setting the lowercased name of the emotes created before search matched on it
+// Only one node does it, the others skip it
+func StartEmoteNameBackfill(ctx context.Context) {
+	go func() {
+		lock, err := redis.GetLocker().Obtain(ctx, "lock:emote-name-backfill", emoteNameBackfillLockDuration, nil)
+		if err != nil {
+			if err != redislock.ErrNotObtained {
+				log.Errorf("redis, err=%v", err)
+			}
+			return
+		}
+		defer func() {
+			_ = lock.Release(ctx)
+		}()
+
+		if err := BackfillEmoteNames(ctx, lock); err != nil {
+			log.Errorf("emote names, err=%v", err)
+		}
+	}()
+}
+
+// Set the lowercased name of every emote missing it, in batches
+// Emotes without it are found through the index on name_lower, so this is cheap once they all have one
+func BackfillEmoteNames(ctx context.Context, lock *redislock.Lock) error {
+	total := int64(0)
+	for {
+		emotes := []*datastructure.Emote{}
+		cur, err := mongo.Database.Collection("emotes").Find(ctx, bson.M{
+			"name_lower": nil,
+		}, options.Find().SetLimit(emoteNameBackfillBatchSize).SetProjection(bson.M{"_id": 1}))
+		if err == nil {
+			err = cur.All(ctx, &emotes)
+		}
+		if err != nil {
+			return err
+		}
+		if len(emotes) == 0 {
+			break
+		}
+
+		ids := make([]interface{}, len(emotes))
+		for i, e := range emotes {
+			ids[i] = e.ID
+		}
+		res, err := mongo.Database.Collection("emotes").UpdateMany(ctx, bson.M{
+			"_id":        bson.M{"$in": ids},
+			"name_lower": nil,
+		}, mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"name_lower": bson.M{"$toLower": "$name"}}}},
+		})
+		if err != nil {
+			return err
+		}
+		total += res.ModifiedCount
+
+		if err := lock.Refresh(ctx, emoteNameBackfillLockDuration, nil); err != nil {
+			return err
+		}
+	}
+
+	if total > 0 {
+		log.Infof("emote names, set the lowercased name of %d emotes", total)
+	}
+	return nil
+}
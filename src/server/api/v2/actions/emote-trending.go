@@ -0,0 +1,128 @@
+package actions
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// How often the trending scores are recomputed
+const emoteTrendingInterval = time.Minute * 5
+
+// The duration of each trending window
+var EmoteTrendingWindows = map[string]time.Duration{
+	datastructure.EmoteTrendingWindowDay:   time.Hour * 24,
+	datastructure.EmoteTrendingWindowWeek:  time.Hour * 24 * 7,
+	datastructure.EmoteTrendingWindowMonth: time.Hour * 24 * 30,
+}
+
+// Start the worker recomputing the trending scores of emotes
+func StartEmoteTrendingWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(emoteTrendingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := UpdateEmoteTrendingScores(ctx); err != nil {
+					log.Errorf("emote trending, err=%v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Recompute the trending scores from the channel events of the longest window
+//
+// The scores are the amount of channels which added the emote minus the amount which removed it within each window.
+// Emotes without events in any window have their scores removed
+func UpdateEmoteTrendingScores(ctx context.Context) error {
+	now := time.Now()
+
+	delta := bson.M{"$cond": bson.A{"$added", 1, -1}}
+	within := func(window string) bson.M {
+		return bson.M{"$sum": bson.M{"$cond": bson.A{
+			bson.M{"$gte": bson.A{"$timestamp", now.Add(-EmoteTrendingWindows[window])}},
+			delta,
+			0,
+		}}}
+	}
+
+	cur, err := mongo.Database.Collection("emote_channel_events").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"timestamp": bson.M{"$gte": now.Add(-EmoteTrendingWindows[datastructure.EmoteTrendingWindowMonth])},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":   "$emote_id",
+			"day":   within(datastructure.EmoteTrendingWindowDay),
+			"week":  within(datastructure.EmoteTrendingWindowWeek),
+			"month": bson.M{"$sum": delta},
+		}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	scores := []struct {
+		EmoteID primitive.ObjectID `bson:"_id"`
+		Day     int32              `bson:"day"`
+		Week    int32              `bson:"week"`
+		Month   int32              `bson:"month"`
+	}{}
+	if err := cur.All(ctx, &scores); err != nil {
+		return err
+	}
+
+	if len(scores) > 0 {
+		models := make([]mongo.WriteModel, len(scores))
+		for i, s := range scores {
+			models[i] = mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": s.EmoteID}).
+				SetUpdate(bson.M{"$set": bson.M{"trending": &datastructure.EmoteTrendingScores{
+					Day:       s.Day,
+					Week:      s.Week,
+					Month:     s.Month,
+					UpdatedAt: now,
+				}}})
+		}
+		if _, err := mongo.Database.Collection("emotes").BulkWrite(ctx, models); err != nil {
+			return err
+		}
+	}
+
+	// Remove the scores of emotes which had no events within the longest window
+	_, err = mongo.Database.Collection("emotes").UpdateMany(ctx, bson.M{
+		"trending.updated_at": bson.M{"$lt": now},
+	}, bson.M{
+		"$unset": bson.M{"trending": ""},
+	})
+
+	return err
+}
+
+// Record channels adding and removing emotes, from which the trending scores are computed
+func recordChannelEmoteEvents(ctx context.Context, channelID primitive.ObjectID, added []primitive.ObjectID, removed []primitive.ObjectID) {
+	now := time.Now()
+	events := make([]interface{}, 0, len(added)+len(removed))
+	for _, id := range added {
+		events = append(events, &datastructure.EmoteChannelEvent{EmoteID: id, ChannelID: channelID, Added: true, Timestamp: now})
+	}
+	for _, id := range removed {
+		events = append(events, &datastructure.EmoteChannelEvent{EmoteID: id, ChannelID: channelID, Added: false, Timestamp: now})
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	if _, err := mongo.Database.Collection("emote_channel_events").InsertMany(ctx, events); err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+}
@@ -0,0 +1,183 @@
+package actions
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// The redis channel on which changes to the global emotes are published
+const GlobalEmotesChannel = "emotes:global"
+
+// How often the global emotes are brought in line with the active global emote sets
+const globalEmoteSetSyncInterval = time.Second * 30
+
+// Start the worker keeping the global emotes in line with the active global emote sets
+func StartGlobalEmoteSetWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(globalEmoteSetSyncInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := SyncGlobalEmotes(ctx); err != nil {
+					log.Errorf("global emote sets, err=%v", err)
+				}
+			}
+		}
+	}()
+}
+
+// Get a query matching the global emote sets which are active at the specified time
+func ActiveGlobalEmoteSetFilter(t time.Time) bson.M {
+	return bson.M{
+		"$and": bson.A{
+			bson.M{"$or": bson.A{bson.M{"start_at": nil}, bson.M{"start_at": bson.M{"$lte": t}}}},
+			bson.M{"$or": bson.A{bson.M{"end_at": nil}, bson.M{"end_at": bson.M{"$gt": t}}}},
+		},
+	}
+}
+
+// Get the global emote sets which are active at the specified time
+func GetActiveGlobalEmoteSets(ctx context.Context, t time.Time) ([]*datastructure.GlobalEmoteSet, error) {
+	sets := []*datastructure.GlobalEmoteSet{}
+	cur, err := mongo.Database.Collection("global_emote_sets").Find(ctx, ActiveGlobalEmoteSetFilter(t))
+	if err == nil {
+		err = cur.All(ctx, &sets)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return sets, nil
+}
+
+// Get the IDs of the emotes in the union of the global emote sets active at the specified time
+func GetActiveGlobalEmoteIDs(ctx context.Context, t time.Time) ([]primitive.ObjectID, error) {
+	sets, err := GetActiveGlobalEmoteSets(ctx, t)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, s := range sets {
+		for _, id := range s.EmoteIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
+
+// Bring the global visibility flag of emotes in line with the active global emote sets
+//
+// Every node may run this at the same time: each emote is only updated if its flag is not in the desired state yet,
+// so a change is carried out and published by exactly one node.
+// Nothing is changed while there are no global emote sets, so that global emotes flagged by hand are kept
+func SyncGlobalEmotes(ctx context.Context) error {
+	exist, err := GlobalEmoteSetsExist(ctx)
+	if err != nil || !exist {
+		return err
+	}
+
+	active, err := GetActiveGlobalEmoteIDs(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Flag the emotes of active sets as global
+	for _, id := range active {
+		res, err := mongo.Database.Collection("emotes").UpdateOne(ctx, bson.M{
+			"_id":        id,
+			"visibility": bson.M{"$bitsAllClear": datastructure.EmoteVisibilityGlobal},
+		}, bson.M{
+			"$bit": bson.M{"visibility": bson.M{"or": datastructure.EmoteVisibilityGlobal}},
+		})
+		if err != nil {
+			return err
+		}
+		if res.ModifiedCount > 0 {
+			publishGlobalEmote(ctx, id, false)
+		}
+	}
+
+	// Unflag global emotes which are no longer part of an active set
+	cur, err := mongo.Database.Collection("emotes").Find(ctx, bson.M{
+		"_id":        bson.M{"$nin": active},
+		"visibility": bson.M{"$bitsAllSet": datastructure.EmoteVisibilityGlobal},
+	}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return err
+	}
+
+	stale := []*datastructure.Emote{}
+	if err := cur.All(ctx, &stale); err != nil {
+		return err
+	}
+	for _, e := range stale {
+		res, err := mongo.Database.Collection("emotes").UpdateOne(ctx, bson.M{
+			"_id":        e.ID,
+			"visibility": bson.M{"$bitsAllSet": datastructure.EmoteVisibilityGlobal},
+		}, bson.M{
+			"$bit": bson.M{"visibility": bson.M{"and": ^datastructure.EmoteVisibilityGlobal}},
+		})
+		if err != nil {
+			return err
+		}
+		if res.ModifiedCount > 0 {
+			publishGlobalEmote(ctx, e.ID, true)
+		}
+	}
+
+	return nil
+}
+
+// Whether any global emote set exists
+// While one does, the global emotes are the union of the active sets, so the global flag of an emote must not be changed by hand
+func GlobalEmoteSetsExist(ctx context.Context) (bool, error) {
+	count, err := mongo.Database.Collection("global_emote_sets").CountDocuments(ctx, bson.M{}, options.Count().SetLimit(1))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// Get a global emote set by its ID
+func GetGlobalEmoteSet(ctx context.Context, id primitive.ObjectID) (*datastructure.GlobalEmoteSet, error) {
+	set := &datastructure.GlobalEmoteSet{}
+	if err := mongo.Database.Collection("global_emote_sets").FindOne(ctx, bson.M{"_id": id}).Decode(set); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmoteSet
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return set, nil
+}
+
+func publishGlobalEmote(ctx context.Context, emoteID primitive.ObjectID, removed bool) {
+	if err := redis.Publish(ctx, GlobalEmotesChannel, redis.PubSubPayloadGlobalEmotes{
+		Removed: removed,
+		ID:      emoteID.Hex(),
+	}); err != nil {
+		log.Errorf("redis, err=%v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package actions
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Send a notification to a user
+func Notify(ctx context.Context, userID primitive.ObjectID, message string, target *datastructure.Target) {
+	_, err := mongo.Database.Collection("notifications").InsertOne(ctx, &datastructure.Notification{
+		UserID:    userID,
+		Message:   message,
+		Target:    target,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+}
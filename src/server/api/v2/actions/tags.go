@@ -0,0 +1,214 @@
+package actions
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/bsm/redislock"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// How often the amount of emotes per tag is recomputed
+const emoteTagCountInterval = time.Minute * 10
+
+// The redis key set once the tags stored before tags were normalized have been normalized
+const emoteTagsNormalizedKey = "emote-tags:normalized"
+
+// Start the worker maintaining the amount of emotes per tag
+// The lock is held until it expires rather than released, so that the counts are recomputed by one node per interval
+func StartEmoteTagWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(emoteTagCountInterval)
+		defer ticker.Stop()
+
+		normalized := false
+		for {
+			_, err := redis.GetLocker().Obtain(ctx, "lock:emote-tags", emoteTagCountInterval, nil)
+			if err == nil {
+				if !normalized {
+					normalized = normalizeStoredEmoteTagsOnce(ctx)
+				}
+				if err := UpdateEmoteTagCounts(ctx); err != nil {
+					log.Errorf("emote tags, err=%v", err)
+				}
+			} else if err != redislock.ErrNotObtained {
+				log.Errorf("redis, err=%v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Normalize the stored tags unless a node already did, which is recorded in redis
+// Returns whether they are normalized
+func normalizeStoredEmoteTagsOnce(ctx context.Context) bool {
+	done, err := redis.Client.Exists(ctx, emoteTagsNormalizedKey).Result()
+	if err != nil {
+		log.Errorf("redis, err=%v", err)
+		return false
+	}
+	if done > 0 {
+		return true
+	}
+
+	if err := NormalizeStoredEmoteTags(ctx); err != nil {
+		log.Errorf("emote tags, err=%v", err)
+		return false
+	}
+	if err := redis.Client.Set(ctx, emoteTagsNormalizedKey, 1, 0).Err(); err != nil {
+		log.Errorf("redis, err=%v", err)
+	}
+
+	return true
+}
+
+// Recompute the amount of public emotes per tag into the emote_tags collection
+// Tags written before normalization are folded and mapped to their synonym, so that they are counted once
+func UpdateEmoteTagCounts(ctx context.Context) error {
+	_, err := mongo.Database.Collection("emotes").Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"status":     bson.M{"$in": datastructure.EmoteStatusesUsable},
+			"visibility": bson.M{"$bitsAllClear": int32(datastructure.EmoteVisibilityPrivate | datastructure.EmoteVisibilityHidden)},
+			"tags.0":     bson.M{"$exists": true},
+		}}},
+		{{Key: "$unwind", Value: "$tags"}},
+		{{Key: "$project", Value: bson.M{"tag": bson.M{"$toLower": "$tags"}}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "tag_synonyms",
+			"localField":   "tag",
+			"foreignField": "_id",
+			"as":           "synonym",
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$synonym.tag", 0}}, "$tag"}},
+			// Each emote is counted once per tag, even if it had several spellings of it
+			"emotes": bson.M{"$addToSet": "$_id"},
+		}}},
+		{{Key: "$project", Value: bson.M{"count": bson.M{"$size": "$emotes"}}}},
+		{{Key: "$out", Value: "emote_tags"}},
+	})
+
+	return err
+}
+
+// Normalize tags before they are written: tags are case folded and replaced by their synonym, duplicates are removed
+func NormalizeEmoteTags(ctx context.Context, tags []string) ([]string, error) {
+	folded := make([]string, len(tags))
+	for i, t := range tags {
+		folded[i] = strings.ToLower(t)
+	}
+
+	synonyms := []*datastructure.TagSynonym{}
+	if len(folded) > 0 {
+		cur, err := mongo.Database.Collection("tag_synonyms").Find(ctx, bson.M{
+			"_id": bson.M{"$in": folded},
+		})
+		if err == nil {
+			err = cur.All(ctx, &synonyms)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	replace := make(map[string]string, len(synonyms))
+	for _, s := range synonyms {
+		replace[s.Synonym] = s.Tag
+	}
+
+	result := []string{}
+	seen := map[string]bool{}
+	for _, t := range folded {
+		if r, ok := replace[t]; ok {
+			t = r
+		}
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+
+	return result, nil
+}
+
+// Normalize the tags of the emotes written before tags were normalized
+// Only emotes with tags which aren't case folded are matched, so this does nothing once they have been normalized
+func NormalizeStoredEmoteTags(ctx context.Context) error {
+	cur, err := mongo.Database.Collection("emotes").Find(ctx, bson.M{
+		"tags": bson.M{"$regex": `\p{Lu}`},
+	}, options.Find().SetProjection(bson.M{"tags": 1}))
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	count := 0
+	for cur.Next(ctx) {
+		emote := &datastructure.Emote{}
+		if err := cur.Decode(emote); err != nil {
+			return err
+		}
+
+		tags, err := NormalizeEmoteTags(ctx, emote.Tags)
+		if err != nil {
+			return err
+		}
+
+		// The tags are only replaced if they weren't changed in the meantime
+		res, err := mongo.Database.Collection("emotes").UpdateOne(ctx, bson.M{
+			"_id":  emote.ID,
+			"tags": emote.Tags,
+		}, bson.M{
+			"$set": bson.M{"tags": tags},
+		})
+		if err != nil {
+			return err
+		}
+		count += int(res.ModifiedCount)
+	}
+	if err := cur.Err(); err != nil {
+		return err
+	}
+
+	if count > 0 {
+		log.Infof("emote tags, normalized the tags of %d emotes", count)
+	}
+	return nil
+}
+
+// Replace a tag by its synonym on every emote, keeping the order of the tags and removing duplicates
+// Tags are matched ignoring case, as emotes may hold tags written before normalization
+func ApplyTagSynonym(ctx context.Context, synonym string, tag string) error {
+	synonym = strings.ToLower(synonym)
+	_, err := mongo.Database.Collection("emotes").UpdateMany(ctx, bson.M{
+		"tags": bson.M{"$regex": "^" + regexp.QuoteMeta(synonym) + "$", "$options": "i"},
+	}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{"tags": bson.M{"$reduce": bson.M{
+			"input": bson.M{"$map": bson.M{
+				"input": "$tags",
+				"as":    "t",
+				"in":    bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{bson.M{"$toLower": "$$t"}, synonym}}, tag, "$$t"}},
+			}},
+			"initialValue": bson.A{},
+			"in": bson.M{"$cond": bson.A{
+				bson.M{"$in": bson.A{"$$this", "$$value"}},
+				"$$value",
+				bson.M{"$concatArrays": bson.A{"$$value", bson.A{"$$this"}}},
+			}},
+		}}}}},
+	})
+
+	return err
+}
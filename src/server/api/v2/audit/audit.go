@@ -0,0 +1,226 @@
+package api_audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/middleware"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+var (
+	errInternalServer = []byte(`{"status":500,"message":"internal server error"}`)
+	errInvalidRequest = `{"status":400,"message":"%s"}`
+	errAccessDenied   = `{"status":403,"message":"%s"}`
+)
+
+// The longest an export may keep streaming
+const exportTimeout = 30 * time.Minute
+
+var csvHeader = []string{"id", "type", "created_at", "actor_id", "target_type", "target_id", "reason", "changes", "ip", "user_agent", "request_id", "node"}
+
+func Audit(app fiber.Router) fiber.Router {
+	group := app.Group("/audit")
+
+	//
+	// Export audit entries
+	//
+	// Query parameters:
+	// format: ndjson (default) or csv
+	// since, until: the time range as RFC3339 timestamps, since is required and until defaults to now
+	// type: a comma separated list of audit log types
+	// actor_id, target_type, target_id: only include entries with this actor or target
+	//
+	group.Get("/export", middleware.UserAuthMiddleware(true), func(c *fiber.Ctx) error {
+		c.Set("Content-Type", "application/json")
+		usr, ok := c.Locals("user").(*datastructure.User)
+		if !ok {
+			return c.Status(500).Send(errInternalServer)
+		}
+		if err := authorization.Authorize(c.Context(), usr, authorization.Administrate, nil); err != nil {
+			return c.Status(403).Send(utils.S2B(fmt.Sprintf(errAccessDenied, err.Error())))
+		}
+
+		format := c.Query("format", "ndjson")
+		if format != "ndjson" && format != "csv" {
+			return c.Status(400).Send(utils.S2B(fmt.Sprintf(errInvalidRequest, "Unknown Format")))
+		}
+
+		f, err := parseFilter(c)
+		if err != nil {
+			return c.Status(400).Send(utils.S2B(fmt.Sprintf(errInvalidRequest, err.Error())))
+		}
+
+		// The export keeps streaming after the handler returns, so it can't use the request's context
+		ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+		cur, err := mongo.Database.Collection("audit").Find(ctx, f.Match(), options.Find().SetSort(bson.M{"_id": 1}))
+		if err != nil {
+			cancel()
+			log.Errorf("mongo, err=%v", err)
+			return c.Status(500).Send(errInternalServer)
+		}
+
+		filename := fmt.Sprintf("audit-%s.%s", f.Since.UTC().Format("20060102T150405"), format)
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if format == "csv" {
+			c.Set("Content-Type", "text/csv")
+		} else {
+			c.Set("Content-Type", "application/x-ndjson")
+		}
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+			defer cur.Close(ctx)
+
+			var cw *csv.Writer
+			if format == "csv" {
+				cw = csv.NewWriter(w)
+				_ = cw.Write(csvHeader)
+			}
+
+			for i := 0; cur.Next(ctx); i++ {
+				entry := &datastructure.AuditLog{}
+				if err := cur.Decode(entry); err != nil {
+					log.Errorf("mongo, err=%v", err)
+					return
+				}
+
+				if err := writeRecord(w, cw, audit.NewRecord(entry)); err != nil {
+					log.Errorf("audit export, err=%v", err)
+					return
+				}
+
+				// Flush regularly so that the client receives the export as it is read
+				if i%100 == 99 {
+					if cw != nil {
+						cw.Flush()
+					}
+					if err := w.Flush(); err != nil {
+						// The client went away
+						return
+					}
+				}
+			}
+			if err := cur.Err(); err != nil {
+				log.Errorf("mongo, err=%v", err)
+			}
+
+			if cw != nil {
+				cw.Flush()
+			}
+			_ = w.Flush()
+		})
+
+		return nil
+	})
+
+	return group
+}
+
+func writeRecord(w *bufio.Writer, cw *csv.Writer, r *audit.Record) error {
+	if cw == nil {
+		b, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+		return w.WriteByte('\n')
+	}
+
+	changes, err := json.Marshal(r.Changes)
+	if err != nil {
+		return err
+	}
+
+	req := r.Request
+	if req == nil {
+		req = &datastructure.AuditLogRequest{}
+	}
+
+	return cw.Write([]string{
+		r.ID,
+		strconv.Itoa(int(r.Type)),
+		r.CreatedAt.UTC().Format(time.RFC3339),
+		r.ActorID,
+		r.TargetType,
+		r.TargetID,
+		r.Reason,
+		string(changes),
+		req.IP,
+		req.UserAgent,
+		req.RequestID,
+		req.Node,
+	})
+}
+
+// Read the export filter from the query parameters
+func parseFilter(c *fiber.Ctx) (audit.Filter, error) {
+	f := audit.Filter{}
+
+	since, err := time.Parse(time.RFC3339, c.Query("since"))
+	if err != nil {
+		return f, fmt.Errorf("Invalid Start Time")
+	}
+	until := time.Now()
+	if s := c.Query("until"); s != "" {
+		if until, err = time.Parse(time.RFC3339, s); err != nil {
+			return f, fmt.Errorf("Invalid End Time")
+		}
+	}
+	if !until.After(since) {
+		return f, fmt.Errorf("Invalid Time Range")
+	}
+	f.Since = &since
+	f.Until = &until
+
+	if s := c.Query("type"); s != "" {
+		for _, t := range strings.Split(s, ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(t))
+			if err != nil {
+				return f, fmt.Errorf("Invalid Type")
+			}
+			f.Types = append(f.Types, int32(n))
+		}
+	}
+
+	if s := c.Query("actor_id"); s != "" {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return f, fmt.Errorf("Invalid Actor ID")
+		}
+		f.ActorID = &id
+	}
+
+	if s := c.Query("target_type"); s != "" {
+		f.TargetType = &s
+	}
+
+	if s := c.Query("target_id"); s != "" {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return f, fmt.Errorf("Invalid Target ID")
+		}
+		f.TargetID = &id
+	}
+
+	return f, nil
+}
@@ -16,6 +16,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/aws"
 	"github.com/SevenTV/ServerGo/src/configure"
 	"github.com/SevenTV/ServerGo/src/discord"
@@ -46,7 +47,7 @@ func CreateRoute(router fiber.Router) {
 			if !ok {
 				return 500, errInternalServer, nil
 			}
-			if !usr.HasPermission(datastructure.RolePermissionEmoteCreate) {
+			if !authorization.Can(c.Context(), usr, authorization.EmoteCreate, nil) {
 				return 403, utils.S2B(fmt.Sprintf(errAccessDenied, "You don't have permission to do that.")), nil
 			}
 
@@ -155,19 +156,19 @@ func CreateRoute(router fiber.Router) {
 				return 400, utils.S2B(fmt.Sprintf(errInvalidRequest, "The fields were not provided.")), nil
 			}
 
-			if !usr.HasPermission(datastructure.RolePermissionManageUsers) {
-				if channelID.Hex() != usr.ID.Hex() {
-					if err := mongo.Database.Collection("users").FindOne(c.Context(), bson.M{
-						"_id":     channelID,
-						"editors": usr.ID,
-					}).Err(); err != nil {
-						if err == mongo.ErrNoDocuments {
-							return 403, utils.S2B(fmt.Sprintf(errAccessDenied, "You don't have permission to do that.")), nil
-						}
-						log.Errorf("mongo, err=%v", err)
-						return 500, errInternalServer, nil
-					}
+			channel := &datastructure.User{}
+			if err := mongo.Database.Collection("users").FindOne(c.Context(), bson.M{"_id": channelID}).Decode(channel); err != nil {
+				if err == mongo.ErrNoDocuments {
+					return 400, utils.S2B(fmt.Sprintf(errInvalidRequest, "Unknown Channel")), nil
 				}
+				log.Errorf("mongo, err=%v", err)
+				return 500, errInternalServer, nil
+			}
+			if err := authorization.Authorize(c.Context(), usr, authorization.ChannelEmoteUpload, channel); err != nil {
+				if err == authorization.ErrInternal {
+					return 500, errInternalServer, nil
+				}
+				return 403, utils.S2B(fmt.Sprintf(errAccessDenied, "You don't have permission to do that.")), nil
 			}
 
 			// Get uploaded image file into an image.Image
@@ -262,6 +263,7 @@ func CreateRoute(router fiber.Router) {
 
 			emote = &datastructure.Emote{
 				Name:             emoteName,
+				NameLower:        strings.ToLower(emoteName),
 				Mime:             mime,
 				Status:           datastructure.EmoteStatusProcessing,
 				Tags:             []string{},
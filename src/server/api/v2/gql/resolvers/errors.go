@@ -3,27 +3,73 @@ package resolvers
 import (
 	"fmt"
 
-	"github.com/SevenTV/ServerGo/src/configure"
+	"github.com/SevenTV/ServerGo/src/authorization"
 )
 
 var (
-	ErrInvalidName           = fmt.Errorf("Invalid Name")
-	ErrLoginRequired         = fmt.Errorf("Authentication Required")
-	ErrInvalidOwner          = fmt.Errorf("Invalid Owner ID")
-	ErrInvalidTags           = fmt.Errorf("Too Many Tags (10)")
-	ErrInvalidTag            = fmt.Errorf("Invalid Tags")
-	ErrInvalidUpdate         = fmt.Errorf("Invalid Update")
-	ErrUnknownEmote          = fmt.Errorf("Unknown Emote")
-	ErrUnknownChannel        = fmt.Errorf("Unknown Channel")
-	ErrUnknownUser           = fmt.Errorf("Unknown User")
-	ErrAccessDenied          = fmt.Errorf("Insufficient Privilege")
-	ErrUserBanned            = fmt.Errorf("User Is Banned")
-	ErrUserNotBanned         = fmt.Errorf("User Is Not Banned")
-	ErrYourself              = fmt.Errorf("Don't Be Silly")
-	ErrNoReason              = fmt.Errorf("No Reason")
-	ErrInternalServer        = fmt.Errorf("Internal Server Error")
-	ErrDepth                 = fmt.Errorf("Max Depth Exceeded (%v)", MaxDepth)
-	ErrQueryLimit            = fmt.Errorf("Max Query Limit Exceeded (%v)", QueryLimit)
-	ErrInvalidSortOrder      = fmt.Errorf("SortOrder is either 0 (descending) or 1 (ascending)")
-	ErrEmoteSlotLimitReached = fmt.Errorf("Channel Emote Slots Limit Reached (%v)", configure.Config.GetInt("limits.meta.channel_emote_slots"))
+	ErrInvalidName      = fmt.Errorf("Invalid Name")
+	ErrLoginRequired    = fmt.Errorf("Authentication Required")
+	ErrInvalidOwner     = fmt.Errorf("Invalid Owner ID")
+	ErrInvalidTags      = fmt.Errorf("Too Many Tags (10)")
+	ErrInvalidTag       = fmt.Errorf("Invalid Tags")
+	ErrInvalidUpdate    = fmt.Errorf("Invalid Update")
+	ErrUnknownEmote     = fmt.Errorf("Unknown Emote")
+	ErrUnknownChannel   = fmt.Errorf("Unknown Channel")
+	ErrUnknownUser      = fmt.Errorf("Unknown User")
+	ErrAccessDenied     = authorization.ErrAccessDenied
+	ErrUserBanned       = authorization.ErrBanned
+	ErrUserNotBanned    = fmt.Errorf("User Is Not Banned")
+	ErrYourself         = fmt.Errorf("Don't Be Silly")
+	ErrNoReason         = fmt.Errorf("No Reason")
+	ErrInternalServer   = authorization.ErrInternal
+	ErrDepth            = fmt.Errorf("Max Depth Exceeded (%v)", MaxDepth)
+	ErrQueryLimit       = fmt.Errorf("Max Query Limit Exceeded (%v)", QueryLimit)
+	ErrInvalidSortOrder = fmt.Errorf("SortOrder is either 0 (descending) or 1 (ascending)")
+	ErrInvalidAmount    = fmt.Errorf("Invalid Amount")
+	ErrUnknownGrant     = fmt.Errorf("Unknown Grant")
+	ErrUnknownSchedule  = fmt.Errorf("Unknown Schedule")
+	ErrUnknownEmoteSet  = fmt.Errorf("Unknown Emote Set")
+
+	ErrInvalidScheduleAction = fmt.Errorf("Invalid Schedule Action (add, remove, set)")
+	ErrInvalidScheduleTime   = fmt.Errorf("Schedule Must Be In The Future")
+	ErrScheduleNotPending    = fmt.Errorf("Schedule Is No Longer Pending")
+
+	ErrEmoteAlreadyGlobal     = fmt.Errorf("Emote Is Already Global")
+	ErrEmoteAlreadyPending    = fmt.Errorf("Emote Is Already Awaiting Review")
+	ErrEmoteNotPending        = fmt.Errorf("Emote Is Not Awaiting Review")
+	ErrGlobalEmoteSetRequired = fmt.Errorf("A Global Emote Set Is Required While Global Emote Sets Exist")
+
+	ErrEmoteAlreadyDisabled = fmt.Errorf("Emote Is Already Disabled")
+	ErrEmoteNotDisabled     = fmt.Errorf("Emote Is Not Disabled")
+
+	ErrInvalidTrendingWindow = fmt.Errorf("Invalid Trending Window (day, week, month)")
+	ErrInvalidCursor         = fmt.Errorf("Invalid Cursor")
+	ErrInvalidFilter         = fmt.Errorf("Invalid Filter")
+	ErrUnknownTagSynonym     = fmt.Errorf("Unknown Tag Synonym")
+	ErrEmoteAlreadyFavorite  = fmt.Errorf("Emote Is Already A Favorite")
+	ErrEmoteNotFavorite      = fmt.Errorf("Emote Is Not A Favorite")
+
+	ErrUnknownAuditEntry = fmt.Errorf("Unknown Audit Log Entry")
+	ErrNotRevertible     = fmt.Errorf("Audit Log Entry Can Not Be Reverted")
+	ErrAlreadyReverted   = fmt.Errorf("Audit Log Entry Has Already Been Reverted")
+
+	ErrUnknownRole        = fmt.Errorf("Unknown Role")
+	ErrInvalidPosition    = fmt.Errorf("Invalid Position")
+	ErrInvalidPermissions = fmt.Errorf("Invalid Permissions")
+	ErrNoRole             = fmt.Errorf("User Has No Role")
+
+	ErrNotEditor               = fmt.Errorf("User Is Not An Editor")
+	ErrAlreadyEditor           = fmt.Errorf("User Is Already An Editor")
+	ErrUnknownInvitation       = fmt.Errorf("Unknown Invitation")
+	ErrInvitationNoLongerValid = fmt.Errorf("Invitation Is No Longer Valid")
+
+	ErrUnknownAccessToken      = fmt.Errorf("Unknown Access Token")
+	ErrAccessTokenNotAllowed   = fmt.Errorf("Not Allowed With An Access Token")
+	ErrInvalidScopes           = fmt.Errorf("Invalid Scopes")
+	ErrInvalidExpiry           = fmt.Errorf("Invalid Expiry (must be in the future and within %v days)", int(MaxAccessTokenLifetime.Hours()/24))
+	ErrAccessTokenLimitReached = fmt.Errorf("Access Token Limit Reached (%v)", MaxAccessTokens)
 )
+
+func ErrEmoteSlotLimitReached(limit int32) error {
+	return fmt.Errorf("Channel Emote Slots Limit Reached (%v)", limit)
+}
@@ -0,0 +1,184 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/jwt"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/server/middleware"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type createdAccessTokenResolver struct {
+	token       string
+	accessToken *query_resolvers.AccessTokenResolver
+}
+
+func (r *createdAccessTokenResolver) Token() string {
+	return r.token
+}
+
+func (r *createdAccessTokenResolver) AccessToken() *query_resolvers.AccessTokenResolver {
+	return r.accessToken
+}
+
+//
+// CREATE ACCESS TOKEN
+//
+// The token itself is only returned here, and can't be retrieved later
+func (*MutationResolver) CreateAccessToken(ctx context.Context, args struct {
+	Name        string
+	Permissions string
+	Scopes      int32
+	ExpiresAt   string
+}) (*createdAccessTokenResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	// Access tokens can't create others, which could outlive them
+	if usr.AccessToken != nil {
+		return nil, resolvers.ErrAccessTokenNotAllowed
+	}
+
+	name := strings.TrimSpace(args.Name)
+	if len(name) == 0 || len(name) > 32 {
+		return nil, resolvers.ErrInvalidName
+	}
+
+	permissions, err := strconv.ParseInt(args.Permissions, 10, 64)
+	if err != nil || permissions < 0 || permissions > datastructure.RolePermissionAll {
+		return nil, resolvers.ErrInvalidPermissions
+	}
+	if !authorization.CanGrant(usr, permissions) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	scopes := int64(args.Scopes)
+	if scopes < 0 || utils.BitField.RemoveBits(scopes, datastructure.AccessTokenScopeAll) != 0 {
+		return nil, resolvers.ErrInvalidScopes
+	}
+
+	expireAt, err := time.Parse(time.RFC3339, args.ExpiresAt)
+	if err != nil || !expireAt.After(time.Now()) || expireAt.After(time.Now().Add(resolvers.MaxAccessTokenLifetime)) {
+		return nil, resolvers.ErrInvalidExpiry
+	}
+
+	count, err := mongo.Database.Collection("access_tokens").CountDocuments(ctx, bson.M{
+		"user_id":   usr.ID,
+		"expire_at": bson.M{"$gt": time.Now()},
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	if count >= resolvers.MaxAccessTokens {
+		return nil, resolvers.ErrAccessTokenLimitReached
+	}
+
+	token := &datastructure.AccessToken{
+		ID:          primitive.NewObjectID(),
+		UserID:      usr.ID,
+		Name:        name,
+		Permissions: permissions,
+		Scopes:      scopes,
+		ExpireAt:    expireAt,
+		CreatedAt:   time.Now(),
+	}
+
+	signed, err := jwt.Sign(&middleware.PayloadJWT{
+		ID:           usr.ID,
+		TWID:         usr.TwitchID,
+		Permissions:  strconv.FormatInt(permissions, 10),
+		TokenVersion: usr.TokenVersion,
+		TokenID:      &token.ID,
+		CreatedAt:    token.CreatedAt,
+	})
+	if err != nil {
+		log.Errorf("jwt, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	if _, err := mongo.Database.Collection("access_tokens").InsertOne(ctx, token); err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserAccessTokenCreate,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &usr.ID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "access_tokens", OldValue: nil, NewValue: bson.M{"id": token.ID, "name": token.Name, "permissions": token.Permissions, "scopes": token.Scopes, "expire_at": token.ExpireAt}},
+		},
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return &createdAccessTokenResolver{
+		token:       signed,
+		accessToken: query_resolvers.GenerateAccessTokenResolver(ctx, token),
+	}, nil
+}
+
+//
+// REVOKE ACCESS TOKEN
+//
+func (*MutationResolver) RevokeAccessToken(ctx context.Context, args struct {
+	ID string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if usr.AccessToken != nil {
+		return nil, resolvers.ErrAccessTokenNotAllowed
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownAccessToken
+	}
+
+	token := &datastructure.AccessToken{}
+	err = mongo.Database.Collection("access_tokens").FindOneAndDelete(ctx, bson.M{
+		"_id":     id,
+		"user_id": usr.ID,
+	}).Decode(token)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownAccessToken
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserAccessTokenRevoke,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &usr.ID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "access_tokens", OldValue: bson.M{"id": token.ID, "name": token.Name, "permissions": token.Permissions, "scopes": token.Scopes, "expire_at": token.ExpireAt}, NewValue: nil},
+		},
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return &response{
+		Status:  200,
+		Message: "success",
+	}, nil
+}
@@ -0,0 +1,83 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+//
+// Mutate Audit - Revert Entry
+//
+func (*MutationResolver) RevertAuditEntry(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*query_resolvers.AuditRevertResultResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownAuditEntry
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	result, err := actions.RevertAuditEntry(ctx, usr, id, args.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	return query_resolvers.GenerateAuditRevertResultResolver(ctx, result, field.Children), nil
+}
+
+//
+// Mutate Audit - Revert User Actions
+//
+func (*MutationResolver) RevertUserActions(ctx context.Context, args struct {
+	UserID string
+	Since  string
+	Reason *string
+}) ([]*query_resolvers.AuditRevertResultResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	userID, err := primitive.ObjectIDFromHex(args.UserID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownUser
+	}
+	since, err := time.Parse(time.RFC3339, args.Since)
+	if err != nil {
+		return nil, resolvers.ErrInvalidFilter
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	results, err := actions.RevertUserActions(ctx, usr, userID, since, args.Reason)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]*query_resolvers.AuditRevertResultResolver, len(results))
+	for i, r := range results {
+		resolved[i] = query_resolvers.GenerateAuditRevertResultResolver(ctx, r, field.Children)
+	}
+
+	return resolved, nil
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"time"
 
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
 	"github.com/SevenTV/ServerGo/src/redis"
@@ -27,11 +29,6 @@ func (*MutationResolver) BanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrLoginRequired
 	}
 
-	// Verify actor has permission to ban
-	if !usr.HasPermission(datastructure.RolePermissionBanUsers) {
-		return nil, resolvers.ErrAccessDenied
-	}
-
 	// Serialize id to ObjectID
 	id, err := primitive.ObjectIDFromHex(args.VictimID)
 	if err != nil {
@@ -44,12 +41,11 @@ func (*MutationResolver) BanUser(ctx context.Context, args struct {
 	}
 
 	// Check if ban already exists on victim
-	_, err = redis.Client.HGet(ctx, "user:bans", id.Hex()).Result()
-	if err != nil && err != redis.ErrNil {
-		log.Errorf("redis, err=%v", err)
-		return nil, resolvers.ErrInternalServer
+	banned, err := authorization.IsBanned(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	if err == nil {
+	if banned {
 		return nil, resolvers.ErrUserBanned
 	}
 
@@ -73,9 +69,9 @@ func (*MutationResolver) BanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	// Check if actor has a higher role than victim
-	if user.Role.Position >= usr.Role.Position {
-		return nil, resolvers.ErrAccessDenied
+	// Verify actor has permission to ban, and a higher role than victim
+	if err := authorization.Authorize(ctx, usr, authorization.UserBan, user); err != nil {
+		return nil, err
 	}
 
 	reasonN := "no reason"
@@ -108,7 +104,7 @@ func (*MutationResolver) BanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
+	err = audit.Insert(ctx, &datastructure.AuditLog{
 		Type:      datastructure.AuditLogTypeUserBan,
 		CreatedBy: usr.ID,
 		Target:    &datastructure.Target{ID: &id, Type: "users"},
@@ -139,10 +135,6 @@ func (*MutationResolver) UnbanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrLoginRequired
 	}
 
-	if !usr.HasPermission(datastructure.RolePermissionBanUsers) {
-		return nil, resolvers.ErrAccessDenied
-	}
-
 	id, err := primitive.ObjectIDFromHex(args.VictimID)
 	if err != nil {
 		return nil, resolvers.ErrUnknownUser
@@ -152,13 +144,12 @@ func (*MutationResolver) UnbanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrYourself
 	}
 
-	_, err = redis.Client.HGet(ctx, "user:bans", id.Hex()).Result()
+	banned, err := authorization.IsBanned(ctx, id)
 	if err != nil {
-		if err != redis.ErrNil {
-			return nil, resolvers.ErrUserNotBanned
-		}
-		log.Errorf("redis, err=%v", err)
-		return nil, resolvers.ErrInternalServer
+		return nil, err
+	}
+	if !banned {
+		return nil, resolvers.ErrUserNotBanned
 	}
 
 	res := mongo.Database.Collection("users").FindOne(ctx, bson.M{
@@ -181,6 +172,10 @@ func (*MutationResolver) UnbanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
+	if err := authorization.Authorize(ctx, usr, authorization.UserBan, user); err != nil {
+		return nil, err
+	}
+
 	_, err = mongo.Database.Collection("bans").UpdateMany(ctx, bson.M{
 		"user_id": user.ID,
 		"active":  true,
@@ -200,7 +195,7 @@ func (*MutationResolver) UnbanUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
+	err = audit.Insert(ctx, &datastructure.AuditLog{
 		Type:      datastructure.AuditLogTypeUserUnban,
 		CreatedBy: usr.ID,
 		Target:    &datastructure.Target{ID: &id, Type: "users"},
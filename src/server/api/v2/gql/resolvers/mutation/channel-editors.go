@@ -2,10 +2,14 @@ package mutation_resolvers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
-	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
 	"github.com/SevenTV/ServerGo/src/utils"
@@ -18,10 +22,12 @@ import (
 //
 // ADD CHANNEL EDITOR
 //
+// Invites a user to become an editor, which they only become once accepting
 func (*MutationResolver) AddChannelEditor(ctx context.Context, args struct {
-	ChannelID string
-	EditorID  string
-	Reason    *string
+	ChannelID   string
+	EditorID    string
+	Permissions *int32
+	Reason      *string
 }) (*query_resolvers.UserResolver, error) {
 	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
 	if !ok {
@@ -43,23 +49,11 @@ func (*MutationResolver) AddChannelEditor(ctx context.Context, args struct {
 		return nil, resolvers.ErrYourself
 	}
 
-	_, err = redis.Client.HGet(ctx, "user:bans", channelID.Hex()).Result()
-	if err != nil && err != redis.ErrNil {
-		log.Errorf("redis, err=%v", err)
-		return nil, resolvers.ErrInternalServer
-	}
-
-	if err == nil {
-		return nil, resolvers.ErrUserBanned
-	}
-
-	_, err = redis.Client.HGet(ctx, "user:bans", editorID.Hex()).Result()
-	if err != nil && err != redis.ErrNil {
-		log.Errorf("redis, err=%v", err)
-		return nil, resolvers.ErrInternalServer
+	banned, err := authorization.IsBanned(ctx, editorID)
+	if err != nil {
+		return nil, err
 	}
-
-	if err == nil {
+	if banned {
 		return nil, resolvers.ErrUserBanned
 	}
 
@@ -82,50 +76,80 @@ func (*MutationResolver) AddChannelEditor(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	if !usr.HasPermission(datastructure.RolePermissionManageUsers) {
-		if channel.ID.Hex() != usr.ID.Hex() {
-			return nil, resolvers.ErrAccessDenied
+	if err := authorization.Authorize(ctx, usr, authorization.ChannelEditorsManage, channel); err != nil {
+		return nil, err
+	}
+
+	if _, ok := channel.GetEditorPermissions(editorID); ok {
+		return nil, resolvers.ErrAlreadyEditor
+	}
+
+	permissions := datastructure.EditorPermissionDefault
+	if args.Permissions != nil {
+		permissions = int64(*args.Permissions)
+		if permissions < 0 || utils.BitField.RemoveBits(permissions, datastructure.EditorPermissionAll) != 0 {
+			return nil, resolvers.ErrInvalidPermissions
 		}
 	}
+	if !authorization.CanGrantEditor(usr, channel, permissions) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	count, err := mongo.Database.Collection("users").CountDocuments(ctx, bson.M{"_id": editorID})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	if count == 0 {
+		return nil, resolvers.ErrUnknownUser
+	}
 
 	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
 	if failed {
 		return nil, resolvers.ErrDepth
 	}
 
-	var newChannel *datastructure.User
+	// Inviting a user who already has a pending invitation replaces it
+	invitation := &datastructure.EditorInvitation{}
 	after := options.After
-	doc := mongo.Database.Collection("users").FindOneAndUpdate(ctx, bson.M{
-		"_id": channelID,
+	upsert := true
+	err = mongo.Database.Collection("editor_invitations").FindOneAndUpdate(ctx, bson.M{
+		"channel_id": channelID,
+		"user_id":    editorID,
+		"status":     datastructure.EditorInvitationStatusPending,
 	}, bson.M{
-		"$addToSet": bson.M{
-			"editors": editorID,
+		"$set": bson.M{
+			"permissions":   permissions,
+			"reason":        args.Reason,
+			"created_by_id": usr.ID,
+			"created_at":    time.Now(),
 		},
 	}, &options.FindOneAndUpdateOptions{
 		ReturnDocument: &after,
-	})
-	if err := doc.Decode(&newChannel); err != nil {
-		return nil, err
-	}
-
+		Upsert:         &upsert,
+	}).Decode(invitation)
 	if err != nil {
 		log.Errorf("mongo, err=%v", err)
 		return nil, resolvers.ErrInternalServer
 	}
 
-	_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
-		Type:      datastructure.AuditLogTypeUserChannelEditorRemove,
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserChannelEditorInvite,
 		CreatedBy: usr.ID,
 		Target:    &datastructure.Target{ID: &channelID, Type: "users"},
 		Changes: []*datastructure.AuditLogChange{
-			{Key: "editors", OldValue: channel.EditorIDs, NewValue: newChannel.EditorIDs},
+			{Key: "editor_invitations", OldValue: nil, NewValue: editorID.Hex()},
+			{Key: "editor_permissions", OldValue: nil, NewValue: bson.M{editorID.Hex(): permissions}},
 		},
 		Reason: args.Reason,
 	})
 	if err != nil {
 		log.Errorf("mongo, err=%v", err)
 	}
-	return query_resolvers.GenerateUserResolver(ctx, newChannel, &newChannel.ID, field.Children)
+
+	actions.Notify(ctx, editorID, fmt.Sprintf("You have been invited to become an editor of %s", channel.DisplayName), &datastructure.Target{ID: &invitation.ID, Type: "editor_invitations"})
+
+	return query_resolvers.GenerateUserResolver(ctx, channel, &channel.ID, field.Children)
 }
 
 //
@@ -151,16 +175,6 @@ func (*MutationResolver) RemoveChannelEditor(ctx context.Context, args struct {
 		return nil, resolvers.ErrUnknownChannel
 	}
 
-	_, err = redis.Client.HGet(ctx, "user:bans", channelID.Hex()).Result()
-	if err != nil && err != redis.ErrNil {
-		log.Errorf("redis, err=%v", err)
-		return nil, resolvers.ErrInternalServer
-	}
-
-	if err == nil {
-		return nil, resolvers.ErrUserBanned
-	}
-
 	res := mongo.Database.Collection("users").FindOne(ctx, bson.M{
 		"_id": channelID,
 	})
@@ -180,10 +194,14 @@ func (*MutationResolver) RemoveChannelEditor(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	if !usr.HasPermission(datastructure.RolePermissionManageUsers) {
-		if channel.ID.Hex() != usr.ID.Hex() {
-			return nil, resolvers.ErrAccessDenied
-		}
+	if err := authorization.Authorize(ctx, usr, authorization.ChannelEditorsManage, channel); err != nil {
+		return nil, err
+	}
+
+	// Editors can't remove editors holding permissions they lack themselves
+	permissions, isEditor := channel.GetEditorPermissions(editorID)
+	if isEditor && !authorization.CanGrantEditor(usr, channel, permissions) {
+		return nil, resolvers.ErrAccessDenied
 	}
 
 	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
@@ -191,6 +209,44 @@ func (*MutationResolver) RemoveChannelEditor(ctx context.Context, args struct {
 		return nil, resolvers.ErrDepth
 	}
 
+	// Pending invitations are withdrawn as well
+	now := time.Now()
+	cancelled, err := mongo.Database.Collection("editor_invitations").UpdateMany(ctx, bson.M{
+		"channel_id": channelID,
+		"user_id":    editorID,
+		"status":     datastructure.EditorInvitationStatusPending,
+	}, bson.M{
+		"$set": bson.M{
+			"status":      datastructure.EditorInvitationStatusCancelled,
+			"answered_at": now,
+		},
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	if cancelled.ModifiedCount > 0 {
+		err = audit.Insert(ctx, &datastructure.AuditLog{
+			Type:      datastructure.AuditLogTypeUserChannelEditorInviteCancel,
+			CreatedBy: usr.ID,
+			Target:    &datastructure.Target{ID: &channelID, Type: "users"},
+			Changes: []*datastructure.AuditLogChange{
+				{Key: "editor_invitations", OldValue: editorID.Hex(), NewValue: nil},
+			},
+			Reason: args.Reason,
+		})
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+		}
+	}
+
+	if !isEditor {
+		if cancelled.ModifiedCount == 0 {
+			return nil, resolvers.ErrNotEditor
+		}
+		return query_resolvers.GenerateUserResolver(ctx, channel, &channel.ID, field.Children)
+	}
+
 	var newChannel *datastructure.User
 	after := options.After
 	doc := mongo.Database.Collection("users").FindOneAndUpdate(ctx, bson.M{
@@ -199,24 +255,123 @@ func (*MutationResolver) RemoveChannelEditor(ctx context.Context, args struct {
 		"$pull": bson.M{
 			"editors": editorID,
 		},
+		"$unset": bson.M{
+			"editor_permissions." + editorID.Hex(): "",
+		},
 	}, &options.FindOneAndUpdateOptions{
 		ReturnDocument: &after,
 	})
 	if err := doc.Decode(&newChannel); err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// The invitations they created can't be accepted anymore
+	if err := cancelEditorInvitationsBy(ctx, usr, channelID, editorID, args.Reason); err != nil {
 		return nil, err
 	}
 
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserChannelEditorRemove,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &channelID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "editors", OldValue: channel.EditorIDs, NewValue: newChannel.EditorIDs},
+		},
+		Reason: args.Reason,
+	})
 	if err != nil {
 		log.Errorf("mongo, err=%v", err)
+	}
+
+	return query_resolvers.GenerateUserResolver(ctx, newChannel, &newChannel.ID, field.Children)
+}
+
+//
+// SET CHANNEL EDITOR PERMISSIONS
+//
+func (*MutationResolver) SetChannelEditorPermissions(ctx context.Context, args struct {
+	ChannelID   string
+	EditorID    string
+	Permissions int32
+	Reason      *string
+}) (*query_resolvers.UserResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	editorID, err := primitive.ObjectIDFromHex(args.EditorID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownUser
+	}
+
+	channelID, err := primitive.ObjectIDFromHex(args.ChannelID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownChannel
+	}
+
+	permissions := int64(args.Permissions)
+	if permissions < 0 || utils.BitField.RemoveBits(permissions, datastructure.EditorPermissionAll) != 0 {
+		return nil, resolvers.ErrInvalidPermissions
+	}
+
+	channel := &datastructure.User{}
+	if err := mongo.Database.Collection("users").FindOne(ctx, bson.M{"_id": channelID}).Decode(channel); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownChannel
+		}
+		log.Errorf("mongo, err=%v", err)
 		return nil, resolvers.ErrInternalServer
 	}
 
-	_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
-		Type:      datastructure.AuditLogTypeUserChannelEditorRemove,
+	if err := authorization.Authorize(ctx, usr, authorization.ChannelEditorsManage, channel); err != nil {
+		return nil, err
+	}
+
+	oldPermissions, ok := channel.GetEditorPermissions(editorID)
+	if !ok {
+		return nil, resolvers.ErrNotEditor
+	}
+	// Editors can't change their own permissions, nor grant or take away permissions they lack
+	if editorID == usr.ID && channel.ID != usr.ID {
+		return nil, resolvers.ErrYourself
+	}
+	if !authorization.CanGrantEditor(usr, channel, oldPermissions|permissions) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	var newChannel *datastructure.User
+	after := options.After
+	doc := mongo.Database.Collection("users").FindOneAndUpdate(ctx, bson.M{
+		"_id":     channelID,
+		"editors": editorID,
+	}, bson.M{
+		"$set": bson.M{
+			"editor_permissions." + editorID.Hex(): permissions,
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(&newChannel); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrNotEditor
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserChannelEditorPermissionsSet,
 		CreatedBy: usr.ID,
 		Target:    &datastructure.Target{ID: &channelID, Type: "users"},
 		Changes: []*datastructure.AuditLogChange{
-			{Key: "editors", OldValue: channel.EditorIDs, NewValue: newChannel.EditorIDs},
+			{Key: "editor_permissions", OldValue: bson.M{editorID.Hex(): oldPermissions}, NewValue: bson.M{editorID.Hex(): permissions}},
 		},
 		Reason: args.Reason,
 	})
@@ -0,0 +1,192 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//
+// Mutate Channel Emotes - Schedule
+//
+func (*MutationResolver) ScheduleChannelEmotes(ctx context.Context, args struct {
+	ChannelID string
+	Action    string
+	EmoteIDs  []string
+	ExecuteAt string
+	Reason    *string
+}) (*query_resolvers.ChannelEmoteScheduleResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	channelID, err := primitive.ObjectIDFromHex(args.ChannelID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownChannel
+	}
+
+	executeAt, err := time.Parse(time.RFC3339, args.ExecuteAt)
+	if err != nil {
+		return nil, resolvers.ErrInvalidUpdate
+	}
+	if !executeAt.After(time.Now()) {
+		return nil, resolvers.ErrInvalidScheduleTime
+	}
+
+	emoteIDs := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, s := range args.EmoteIDs {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		emoteIDs = append(emoteIDs, id)
+	}
+	if len(emoteIDs) == 0 && args.Action != datastructure.ChannelEmoteScheduleActionSet {
+		return nil, resolvers.ErrInvalidUpdate
+	}
+
+	channel, err := actions.GetChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorization.Authorize(ctx, usr, actions.ChannelEmoteScheduleAuthorization(args.Action), channel); err != nil {
+		return nil, err
+	}
+
+	// Validate the change against the channel as it is now, it is checked again once executed
+	if _, _, err := actions.ApplyChannelEmoteSchedule(ctx, usr, channel, args.Action, emoteIDs); err != nil {
+		return nil, err
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	schedule := &datastructure.ChannelEmoteSchedule{
+		ChannelID:   channelID,
+		Action:      args.Action,
+		EmoteIDs:    emoteIDs,
+		ExecuteAt:   executeAt,
+		Status:      datastructure.ChannelEmoteScheduleStatusPending,
+		Reason:      args.Reason,
+		CreatedByID: usr.ID,
+		CreatedAt:   time.Now(),
+	}
+
+	res, err := mongo.Database.Collection("channel_emote_schedules").InsertOne(ctx, schedule)
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	schedule.ID = res.InsertedID.(primitive.ObjectID)
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserChannelEmoteScheduleCreate,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &channelID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "emote_schedules", OldValue: nil, NewValue: schedule},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return query_resolvers.GenerateChannelEmoteScheduleResolver(ctx, schedule, field.Children)
+}
+
+//
+// Mutate Channel Emotes - Cancel Schedule
+//
+func (*MutationResolver) CancelChannelEmoteSchedule(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*query_resolvers.ChannelEmoteScheduleResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownSchedule
+	}
+
+	schedule := &datastructure.ChannelEmoteSchedule{}
+	if err := mongo.Database.Collection("channel_emote_schedules").FindOne(ctx, bson.M{"_id": id}).Decode(schedule); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownSchedule
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	channel, err := actions.GetChannel(ctx, schedule.ChannelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.ChannelEdit, channel); err != nil {
+		return nil, err
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	// Only pending schedules can be cancelled, a running schedule is already being carried out
+	after := options.After
+	doc := mongo.Database.Collection("channel_emote_schedules").FindOneAndUpdate(ctx, bson.M{
+		"_id":    id,
+		"status": datastructure.ChannelEmoteScheduleStatusPending,
+	}, bson.M{
+		"$set": bson.M{
+			"status":      datastructure.ChannelEmoteScheduleStatusCancelled,
+			"executed_at": time.Now(),
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(schedule); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrScheduleNotPending
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserChannelEmoteScheduleCancel,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &schedule.ChannelID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "emote_schedules", OldValue: schedule.ID, NewValue: nil},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return query_resolvers.GenerateChannelEmoteScheduleResolver(ctx, schedule, field.Children)
+}
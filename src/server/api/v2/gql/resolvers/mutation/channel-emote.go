@@ -2,19 +2,14 @@ package mutation_resolvers
 
 import (
 	"context"
-	"fmt"
 
-	"github.com/SevenTV/ServerGo/src/configure"
-	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
-	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
 	"github.com/SevenTV/ServerGo/src/utils"
-	log "github.com/sirupsen/logrus"
-	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 //
@@ -40,53 +35,16 @@ func (*MutationResolver) AddChannelEmote(ctx context.Context, args struct {
 		return nil, resolvers.ErrUnknownChannel
 	}
 
-	_, err = redis.Client.HGet(ctx, "user:bans", channelID.Hex()).Result()
-	if err != nil && err != redis.ErrNil {
-		log.Errorf("redis, err=%v", err)
-		return nil, resolvers.ErrInternalServer
-	}
-
-	if err == nil {
-		return nil, resolvers.ErrUserBanned
-	}
-
-	res := mongo.Database.Collection("users").FindOne(ctx, bson.M{
-		"_id": channelID,
-	})
-
-	channel := &datastructure.User{}
-
-	err = res.Err()
-
-	if err == nil {
-		err = res.Decode(channel)
-	}
+	channel, err := actions.GetChannel(ctx, channelID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, resolvers.ErrUnknownChannel
-		}
-		log.Errorf("mongo, err=%v", err)
-		return nil, resolvers.ErrInternalServer
+		return nil, err
 	}
 
-	if !usr.HasPermission(datastructure.RolePermissionManageUsers) {
-		if channel.ID.Hex() != usr.ID.Hex() {
-			found := false
-			for _, e := range channel.EditorIDs {
-				if e.Hex() == usr.ID.Hex() {
-					found = true
-					break
-				}
-			}
-			if !found {
-				return nil, resolvers.ErrAccessDenied
-			}
-		}
-
-		maxEmoteSlots := configure.Config.GetInt("limits.meta.channel_emote_slots")
-		if (len(channel.EmoteIDs) + 1) > maxEmoteSlots {
-			return nil, resolvers.ErrEmoteSlotLimitReached
-		}
+	if err := authorization.Authorize(ctx, usr, authorization.ChannelEmotesAdd, channel); err != nil {
+		return nil, err
+	}
+	if err := actions.CheckChannelEmoteSlots(usr, channel, len(channel.EmoteIDs)+1); err != nil {
+		return nil, err
 	}
 
 	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
@@ -100,81 +58,16 @@ func (*MutationResolver) AddChannelEmote(ctx context.Context, args struct {
 		}
 	}
 
-	emoteRes := mongo.Database.Collection("emotes").FindOne(ctx, bson.M{
-		"_id":    emoteID,
-		"status": datastructure.EmoteStatusLive,
-	})
-
-	emote := &datastructure.Emote{}
-	err = emoteRes.Err()
-	if err == nil {
-		err = emoteRes.Decode(emote)
-	}
-	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, resolvers.ErrUnknownEmote
-		}
-		log.Errorf("mongo, err=%v", err)
-		return nil, resolvers.ErrInternalServer
-	}
-
-	sharedWith := []string{}
-	for _, v := range emote.SharedWith {
-		sharedWith = append(sharedWith, v.Hex())
-	}
-
-	if utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityPrivate)) {
-		if emote.OwnerID.Hex() != channelID.Hex() && !utils.Contains(sharedWith, emoteID.Hex()) {
-			return nil, resolvers.ErrUnknownEmote
-		}
-	}
-
-	emoteIDs := append(channel.EmoteIDs, emoteID)
-	after := options.After
-	doc := mongo.Database.Collection("users").FindOneAndUpdate(ctx, bson.M{
-		"_id": channelID,
-	}, bson.M{
-		"$set": bson.M{
-			"emotes": emoteIDs,
-		},
-	}, &options.FindOneAndUpdateOptions{
-		ReturnDocument: &after,
-	})
-	if err := doc.Decode(channel); err != nil {
+	if _, err := actions.GetAddableEmote(ctx, channel, emoteID); err != nil {
 		return nil, err
 	}
 
-	if doc.Err() != nil {
-		log.Errorf("mongo, err=%v", err)
-		return nil, resolvers.ErrInternalServer
-	}
-
-	_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
-		Type:      datastructure.AuditLogTypeUserChannelEmoteAdd,
-		CreatedBy: usr.ID,
-		Target:    &datastructure.Target{ID: &channelID, Type: "users"},
-		Changes: []*datastructure.AuditLogChange{
-			{Key: "emotes", OldValue: channel.EmoteIDs, NewValue: emoteIDs},
-		},
-		Reason: args.Reason,
-	})
+	emoteIDs := append(channel.EmoteIDs, emoteID)
+	channel, err = actions.SetChannelEmotes(ctx, usr, channel, emoteIDs, datastructure.AuditLogTypeUserChannelEmoteAdd, args.Reason)
 	if err != nil {
-		log.Errorf("mongo, err=%v", err)
+		return nil, err
 	}
 
-	// Push event to redis
-	{
-		ids := make([]string, len(channel.EmoteIDs))
-		for i, id := range channel.EmoteIDs {
-			ids[i] = id.Hex()
-		}
-
-		_ = redis.Publish(ctx, fmt.Sprintf("users:%v:emotes", channel.Login), redis.PubSubPayloadUserEmotes{
-			Removed: false,
-			ID:      emoteID.Hex(),
-			Actor:   usr.DisplayName,
-		})
-	}
 	return query_resolvers.GenerateUserResolver(ctx, channel, &channelID, field.Children)
 }
 
@@ -201,48 +94,13 @@ func (*MutationResolver) RemoveChannelEmote(ctx context.Context, args struct {
 		return nil, resolvers.ErrUnknownChannel
 	}
 
-	_, err = redis.Client.HGet(ctx, "user:bans", channelID.Hex()).Result()
-	if err != nil && err != redis.ErrNil {
-		log.Errorf("redis, err=%v", err)
-		return nil, resolvers.ErrInternalServer
-	}
-
-	if err == nil {
-		return nil, resolvers.ErrUserBanned
-	}
-
-	res := mongo.Database.Collection("users").FindOne(ctx, bson.M{
-		"_id": channelID,
-	})
-
-	channel := &datastructure.User{}
-
-	err = res.Err()
-
-	if err == nil {
-		err = res.Decode(channel)
-	}
+	channel, err := actions.GetChannel(ctx, channelID)
 	if err != nil {
-		if err == mongo.ErrNoDocuments {
-			return nil, resolvers.ErrUnknownChannel
-		}
-		log.Errorf("mongo, err=%v", err)
-		return nil, resolvers.ErrInternalServer
+		return nil, err
 	}
 
-	if !usr.HasPermission(datastructure.RolePermissionManageUsers) {
-		if channel.ID.Hex() != usr.ID.Hex() {
-			found := false
-			for _, e := range channel.EditorIDs {
-				if e.Hex() == usr.ID.Hex() {
-					found = true
-					break
-				}
-			}
-			if !found {
-				return nil, resolvers.ErrAccessDenied
-			}
-		}
+	if err := authorization.Authorize(ctx, usr, authorization.ChannelEmotesRemove, channel); err != nil {
+		return nil, err
 	}
 
 	found := false
@@ -266,57 +124,10 @@ func (*MutationResolver) RemoveChannelEmote(ctx context.Context, args struct {
 		return query_resolvers.GenerateUserResolver(ctx, channel, &channelID, field.Children)
 	}
 
-	_, err = mongo.Database.Collection("users").UpdateOne(ctx, bson.M{
-		"_id": channelID,
-	}, bson.M{
-		"$set": bson.M{
-			"emotes": newIds,
-		},
-	})
-	after := options.After
-	doc := mongo.Database.Collection("users").FindOneAndUpdate(ctx, bson.M{
-		"_id": channelID,
-	}, bson.M{
-		"$set": bson.M{
-			"emotes": newIds,
-		},
-	}, &options.FindOneAndUpdateOptions{
-		ReturnDocument: &after,
-	})
-	if err := doc.Decode(channel); err != nil {
-		return nil, err
-	}
-
-	if doc.Err() != nil {
-		log.Errorf("mongo, err=%v", err)
-		return nil, resolvers.ErrInternalServer
-	}
-
-	_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
-		Type:      datastructure.AuditLogTypeUserChannelEmoteRemove,
-		CreatedBy: usr.ID,
-		Target:    &datastructure.Target{ID: &channelID, Type: "users"},
-		Changes: []*datastructure.AuditLogChange{
-			{Key: "emotes", OldValue: channel.EmoteIDs, NewValue: newIds},
-		},
-		Reason: args.Reason,
-	})
+	channel, err = actions.SetChannelEmotes(ctx, usr, channel, newIds, datastructure.AuditLogTypeUserChannelEmoteRemove, args.Reason)
 	if err != nil {
-		log.Errorf("mongo, err=%v", err)
+		return nil, err
 	}
 
-	// Push event to redis
-	{
-		ids := make([]string, len(channel.EmoteIDs))
-		for i, id := range channel.EmoteIDs {
-			ids[i] = id.Hex()
-		}
-
-		_ = redis.Publish(ctx, fmt.Sprintf("users:%v:emotes", channel.Login), redis.PubSubPayloadUserEmotes{
-			Removed: true,
-			ID:      emoteID.Hex(),
-			Actor:   usr.DisplayName,
-		})
-	}
 	return query_resolvers.GenerateUserResolver(ctx, channel, &channelID, field.Children)
 }
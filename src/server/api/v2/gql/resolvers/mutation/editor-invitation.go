@@ -0,0 +1,346 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//
+// ACCEPT EDITOR INVITATION
+//
+func (*MutationResolver) AcceptEditorInvitation(ctx context.Context, args struct {
+	ID string
+}) (*query_resolvers.UserResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.AccountEdit, nil); err != nil {
+		return nil, err
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownInvitation
+	}
+
+	invitation := &datastructure.EditorInvitation{}
+	err = mongo.Database.Collection("editor_invitations").FindOne(ctx, bson.M{
+		"_id":     id,
+		"user_id": usr.ID,
+		"status":  datastructure.EditorInvitationStatusPending,
+	}).Decode(invitation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownInvitation
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// The inviter may have lost the access needed to invite since, or the channel may have been banned
+	if err := verifyEditorInvitation(ctx, invitation); err != nil {
+		if err == resolvers.ErrInternalServer {
+			return nil, err
+		}
+
+		if _, cerr := mongo.Database.Collection("editor_invitations").UpdateOne(ctx, bson.M{
+			"_id":    invitation.ID,
+			"status": datastructure.EditorInvitationStatusPending,
+		}, bson.M{
+			"$set": bson.M{
+				"status":      datastructure.EditorInvitationStatusCancelled,
+				"answered_at": time.Now(),
+			},
+		}); cerr != nil {
+			log.Errorf("mongo, err=%v", cerr)
+		}
+		return nil, err
+	}
+
+	invitation, err = answerEditorInvitation(ctx, usr, args.ID, datastructure.EditorInvitationStatusAccepted)
+	if err != nil {
+		return nil, err
+	}
+
+	var channel *datastructure.User
+	after := options.After
+	err = mongo.Database.Collection("users").FindOneAndUpdate(ctx, bson.M{
+		"_id": invitation.ChannelID,
+	}, bson.M{
+		"$addToSet": bson.M{
+			"editors": usr.ID,
+		},
+		"$set": bson.M{
+			"editor_permissions." + usr.ID.Hex(): invitation.Permissions,
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	}).Decode(&channel)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownChannel
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	oldEditorIDs := []primitive.ObjectID{}
+	for _, id := range channel.EditorIDs {
+		if id != usr.ID {
+			oldEditorIDs = append(oldEditorIDs, id)
+		}
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserChannelEditorInviteAccept,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &invitation.ChannelID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "editors", OldValue: oldEditorIDs, NewValue: channel.EditorIDs},
+			{Key: "editor_permissions", OldValue: nil, NewValue: bson.M{usr.ID.Hex(): invitation.Permissions}},
+		},
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return query_resolvers.GenerateUserResolver(ctx, channel, &channel.ID, field.Children)
+}
+
+//
+// DECLINE EDITOR INVITATION
+//
+func (*MutationResolver) DeclineEditorInvitation(ctx context.Context, args struct {
+	ID string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.AccountEdit, nil); err != nil {
+		return nil, err
+	}
+
+	invitation, err := answerEditorInvitation(ctx, usr, args.ID, datastructure.EditorInvitationStatusDeclined)
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserChannelEditorInviteDecline,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &invitation.ChannelID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "editor_invitations", OldValue: usr.ID.Hex(), NewValue: nil},
+		},
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return &response{
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+//
+// LEAVE CHANNEL
+//
+// Stops being an editor of a channel
+func (*MutationResolver) LeaveChannel(ctx context.Context, args struct {
+	ChannelID string
+	Reason    *string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.AccountEdit, nil); err != nil {
+		return nil, err
+	}
+
+	channelID, err := primitive.ObjectIDFromHex(args.ChannelID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownChannel
+	}
+
+	var channel *datastructure.User
+	after := options.After
+	err = mongo.Database.Collection("users").FindOneAndUpdate(ctx, bson.M{
+		"_id":     channelID,
+		"editors": usr.ID,
+	}, bson.M{
+		"$pull": bson.M{
+			"editors": usr.ID,
+		},
+		"$unset": bson.M{
+			"editor_permissions." + usr.ID.Hex(): "",
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	}).Decode(&channel)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrNotEditor
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// The invitations they created can't be accepted anymore
+	if err := cancelEditorInvitationsBy(ctx, usr, channelID, usr.ID, args.Reason); err != nil {
+		return nil, err
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserChannelEditorLeave,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &channelID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "editors", OldValue: append(append([]primitive.ObjectID{}, channel.EditorIDs...), usr.ID), NewValue: channel.EditorIDs},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return &response{
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+// Verify that the creator of an invitation may still invite an editor with its permissions to the channel
+func verifyEditorInvitation(ctx context.Context, invitation *datastructure.EditorInvitation) error {
+	channel, err := actions.GetChannel(ctx, invitation.ChannelID)
+	if err != nil {
+		return err
+	}
+
+	creator := &datastructure.User{}
+	if err := mongo.Database.Collection("users").FindOne(ctx, bson.M{"_id": invitation.CreatedByID}).Decode(creator); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return resolvers.ErrInvitationNoLongerValid
+		}
+		log.Errorf("mongo, err=%v", err)
+		return resolvers.ErrInternalServer
+	}
+	role := datastructure.GetRole(ctx, creator.RoleID)
+	creator.Role = &role
+
+	if err := authorization.Authorize(ctx, creator, authorization.ChannelEditorsManage, channel); err != nil {
+		if err == authorization.ErrAccessDenied {
+			return resolvers.ErrInvitationNoLongerValid
+		}
+		return err
+	}
+	if !authorization.CanGrantEditor(creator, channel, invitation.Permissions) {
+		return resolvers.ErrInvitationNoLongerValid
+	}
+
+	return nil
+}
+
+// Cancel the pending invitations created by a user in a channel, when they stop being able to invite editors to it
+func cancelEditorInvitationsBy(ctx context.Context, actor *datastructure.User, channelID primitive.ObjectID, creatorID primitive.ObjectID, reason *string) error {
+	invitations := []*datastructure.EditorInvitation{}
+	cur, err := mongo.Database.Collection("editor_invitations").Find(ctx, bson.M{
+		"channel_id":    channelID,
+		"created_by_id": creatorID,
+		"status":        datastructure.EditorInvitationStatusPending,
+	})
+	if err == nil {
+		err = cur.All(ctx, &invitations)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return resolvers.ErrInternalServer
+	}
+
+	for _, invitation := range invitations {
+		res, err := mongo.Database.Collection("editor_invitations").UpdateOne(ctx, bson.M{
+			"_id":    invitation.ID,
+			"status": datastructure.EditorInvitationStatusPending,
+		}, bson.M{
+			"$set": bson.M{
+				"status":      datastructure.EditorInvitationStatusCancelled,
+				"answered_at": time.Now(),
+			},
+		})
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return resolvers.ErrInternalServer
+		}
+		if res.ModifiedCount == 0 {
+			continue // Answered in the meantime
+		}
+
+		err = audit.Insert(ctx, &datastructure.AuditLog{
+			Type:      datastructure.AuditLogTypeUserChannelEditorInviteCancel,
+			CreatedBy: actor.ID,
+			Target:    &datastructure.Target{ID: &channelID, Type: "users"},
+			Changes: []*datastructure.AuditLogChange{
+				{Key: "editor_invitations", OldValue: invitation.UserID.Hex(), NewValue: nil},
+			},
+			Reason: reason,
+		})
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+		}
+	}
+
+	return nil
+}
+
+// Accept or decline a pending invitation of a user
+func answerEditorInvitation(ctx context.Context, usr *datastructure.User, hexID string, status int32) (*datastructure.EditorInvitation, error) {
+	id, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownInvitation
+	}
+
+	invitation := &datastructure.EditorInvitation{}
+	after := options.After
+	err = mongo.Database.Collection("editor_invitations").FindOneAndUpdate(ctx, bson.M{
+		"_id":     id,
+		"user_id": usr.ID,
+		"status":  datastructure.EditorInvitationStatusPending,
+	}, bson.M{
+		"$set": bson.M{
+			"status":      status,
+			"answered_at": time.Now(),
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	}).Decode(invitation)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownInvitation
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return invitation, nil
+}
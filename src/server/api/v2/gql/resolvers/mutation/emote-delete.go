@@ -6,6 +6,8 @@ import (
 	"sync"
 	"time"
 
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/aws"
 	"github.com/SevenTV/ServerGo/src/configure"
 	"github.com/SevenTV/ServerGo/src/discord"
@@ -60,19 +62,8 @@ func (*MutationResolver) DeleteEmote(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	if !usr.HasPermission(datastructure.RolePermissionEmoteEditAll) {
-		if emote.OwnerID.Hex() != usr.ID.Hex() {
-			if err := mongo.Database.Collection("users").FindOne(ctx, bson.M{
-				"_id":     emote.OwnerID,
-				"editors": usr.ID,
-			}).Err(); err != nil {
-				if err == mongo.ErrNoDocuments {
-					return nil, resolvers.ErrAccessDenied
-				}
-				log.Errorf("mongo, err=%v", err)
-				return nil, resolvers.ErrInternalServer
-			}
-		}
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteEdit, emote); err != nil {
+		return nil, err
 	}
 
 	_, err = mongo.Database.Collection("emotes").UpdateOne(ctx, bson.M{
@@ -89,7 +80,7 @@ func (*MutationResolver) DeleteEmote(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
+	err = audit.Insert(ctx, &datastructure.AuditLog{
 		Type:      datastructure.AuditLogTypeEmoteDelete,
 		CreatedBy: usr.ID,
 		Target:    &datastructure.Target{ID: &id, Type: "emotes"},
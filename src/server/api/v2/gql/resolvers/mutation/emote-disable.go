@@ -0,0 +1,189 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/discord"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//
+// Mutate Emote - Disable
+//
+func (*MutationResolver) DisableEmote(ctx context.Context, args struct {
+	ID     string
+	Reason string
+}) (*query_resolvers.EmoteResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	if args.Reason == "" {
+		return nil, resolvers.ErrNoReason
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	emote, err := findModeratedEmote(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if emote.Status == datastructure.EmoteStatusDisabled {
+		return nil, resolvers.ErrEmoteAlreadyDisabled
+	}
+	oldStatus := emote.Status
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	// The emote is kept on the channels which use it, but is no longer served until it is enabled again
+	after := options.After
+	doc := mongo.Database.Collection("emotes").FindOneAndUpdate(ctx, bson.M{
+		"_id":    id,
+		"status": oldStatus,
+	}, bson.M{
+		"$set": bson.M{
+			"status":                datastructure.EmoteStatusDisabled,
+			"status_before_disable": oldStatus,
+			"disable_reason":        args.Reason,
+			"last_modified_date":    time.Now(),
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrEmoteAlreadyDisabled
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeEmoteDisable,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &id, Type: "emotes"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "status", OldValue: oldStatus, NewValue: datastructure.EmoteStatusDisabled},
+		},
+		Reason: &args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	actions.PublishEmoteAvailability(ctx, usr, emote, true)
+	go discord.SendEmoteDisable(*emote, *usr, args.Reason)
+	actions.Notify(ctx, emote.OwnerID, fmt.Sprintf("Your emote %s has been disabled: %s", emote.Name, args.Reason), &datastructure.Target{ID: &id, Type: "emotes"})
+
+	return query_resolvers.GenerateEmoteResolver(ctx, emote, nil, field.Children)
+}
+
+//
+// Mutate Emote - Enable
+//
+func (*MutationResolver) EnableEmote(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*query_resolvers.EmoteResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	// The emote gets back the status it had before being disabled, or becomes live if that status wasn't stored
+	emote := &datastructure.Emote{}
+	after := options.After
+	doc := mongo.Database.Collection("emotes").FindOneAndUpdate(ctx, bson.M{
+		"_id":    id,
+		"status": datastructure.EmoteStatusDisabled,
+	}, mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"status":             bson.M{"$ifNull": bson.A{"$status_before_disable", datastructure.EmoteStatusLive}},
+			"last_modified_date": time.Now(),
+		}}},
+		{{Key: "$unset", Value: bson.A{"disable_reason", "status_before_disable"}}},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrEmoteNotDisabled
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeEmoteEnable,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &id, Type: "emotes"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "status", OldValue: datastructure.EmoteStatusDisabled, NewValue: emote.Status},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	actions.PublishEmoteAvailability(ctx, usr, emote, false)
+	go discord.SendEmoteEnable(*emote, *usr, args.Reason)
+	actions.Notify(ctx, emote.OwnerID, fmt.Sprintf("Your emote %s has been enabled again", emote.Name), &datastructure.Target{ID: &id, Type: "emotes"})
+
+	return query_resolvers.GenerateEmoteResolver(ctx, emote, nil, field.Children)
+}
+
+// Get an emote which can be disabled, deleted emotes and emotes still processing are excluded
+func findModeratedEmote(ctx context.Context, id primitive.ObjectID) (*datastructure.Emote, error) {
+	emote := &datastructure.Emote{}
+	if err := mongo.Database.Collection("emotes").FindOne(ctx, bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": append([]int32{datastructure.EmoteStatusDisabled}, datastructure.EmoteStatusesUsable...)},
+	}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return emote, nil
+}
@@ -2,11 +2,15 @@ package mutation_resolvers
 
 import (
 	"context"
+	"strings"
 	"time"
 
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/discord"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
 	"github.com/SevenTV/ServerGo/src/utils"
@@ -38,6 +42,7 @@ func (*MutationResolver) EditEmote(ctx context.Context, args struct {
 			return nil, resolvers.ErrInvalidName
 		}
 		update["name"] = *req.Name
+		update["name_lower"] = strings.ToLower(*req.Name)
 	}
 	if req.OwnerID != nil {
 		id, err := primitive.ObjectIDFromHex(*req.OwnerID)
@@ -56,6 +61,11 @@ func (*MutationResolver) EditEmote(ctx context.Context, args struct {
 				return nil, resolvers.ErrInvalidTag
 			}
 		}
+		tags, err := actions.NormalizeEmoteTags(ctx, tags)
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
 		update["tags"] = tags
 	}
 	if req.Visibility != nil {
@@ -92,19 +102,8 @@ func (*MutationResolver) EditEmote(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	if !usr.HasPermission(datastructure.RolePermissionEmoteEditAll) {
-		if emote.OwnerID.Hex() != usr.ID.Hex() {
-			if err := mongo.Database.Collection("users").FindOne(ctx, bson.M{
-				"_id":     emote.OwnerID,
-				"editors": usr.ID,
-			}).Err(); err != nil {
-				if err == mongo.ErrNoDocuments {
-					return nil, resolvers.ErrAccessDenied
-				}
-				log.Errorf("mongo, err=%v", err)
-				return nil, resolvers.ErrInternalServer
-			}
-		}
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteEdit, emote); err != nil {
+		return nil, err
 	}
 
 	if req.Name != nil {
@@ -135,7 +134,7 @@ func (*MutationResolver) EditEmote(ctx context.Context, args struct {
 		}
 	}
 	if req.Visibility != nil {
-		if !usr.HasPermission(datastructure.RolePermissionEmoteEditAll) {
+		if !authorization.Can(ctx, usr, authorization.EmoteModerate, nil) {
 			if utils.BitField.HasBits(int64(*req.Visibility), int64(datastructure.EmoteVisibilityGlobal)) {
 				return nil, resolvers.ErrAccessDenied // User tries to set emote's global state but lacks permission
 			}
@@ -146,6 +145,18 @@ func (*MutationResolver) EditEmote(ctx context.Context, args struct {
 			}
 		}
 
+		// While global emote sets exist, an emote is only made global or not by adding it to or removing it from a set
+		if utils.BitField.HasBits(int64(*req.Visibility), int64(datastructure.EmoteVisibilityGlobal)) != utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityGlobal)) {
+			exist, err := actions.GlobalEmoteSetsExist(ctx)
+			if err != nil {
+				log.Errorf("mongo, err=%v", err)
+				return nil, resolvers.ErrInternalServer
+			}
+			if exist {
+				return nil, resolvers.ErrGlobalEmoteSetRequired
+			}
+		}
+
 		if emote.Visibility != update["visibility"] {
 			logChanges = append(logChanges, &datastructure.AuditLogChange{
 				Key:      "visibility",
@@ -180,7 +191,7 @@ func (*MutationResolver) EditEmote(ctx context.Context, args struct {
 			return nil, resolvers.ErrInternalServer
 		}
 
-		_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
+		err = audit.Insert(ctx, &datastructure.AuditLog{
 			Type:      datastructure.AuditLogTypeEmoteEdit,
 			CreatedBy: usr.ID,
 			Target:    &datastructure.Target{ID: &id, Type: "emotes"},
@@ -0,0 +1,148 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//
+// Mutate Emote - Favorite
+//
+func (*MutationResolver) FavoriteEmote(ctx context.Context, args struct {
+	EmoteID string
+}) (*query_resolvers.EmoteResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.AccountEdit, nil); err != nil {
+		return nil, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.EmoteID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	emote := &datastructure.Emote{}
+	if err := mongo.Database.Collection("emotes").FindOne(ctx, bson.M{"_id": id}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	if !emote.IsVisibleTo(usr) {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	// Only count the favorite if it did not exist yet
+	upsert := true
+	res, err := mongo.Database.Collection("emote_favorites").UpdateOne(ctx, bson.M{
+		"user_id":  usr.ID,
+		"emote_id": id,
+	}, bson.M{
+		"$setOnInsert": bson.M{"created_at": time.Now()},
+	}, &options.UpdateOptions{Upsert: &upsert})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	if res.UpsertedCount == 0 {
+		return nil, resolvers.ErrEmoteAlreadyFavorite
+	}
+
+	if err := updateFavoriteCount(ctx, emote, 1); err != nil {
+		return nil, err
+	}
+
+	return query_resolvers.GenerateEmoteResolver(ctx, emote, nil, field.Children)
+}
+
+//
+// Mutate Emote - Unfavorite
+//
+func (*MutationResolver) UnfavoriteEmote(ctx context.Context, args struct {
+	EmoteID string
+}) (*query_resolvers.EmoteResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.AccountEdit, nil); err != nil {
+		return nil, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.EmoteID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	res, err := mongo.Database.Collection("emote_favorites").DeleteOne(ctx, bson.M{
+		"user_id":  usr.ID,
+		"emote_id": id,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	if res.DeletedCount == 0 {
+		return nil, resolvers.ErrEmoteNotFavorite
+	}
+
+	// The emote may be gone already, in which case only the favorite is removed
+	emote := &datastructure.Emote{ID: id}
+	if err := updateFavoriteCount(ctx, emote, -1); err != nil {
+		if err == resolvers.ErrUnknownEmote {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if !emote.IsVisibleTo(usr) {
+		return nil, nil
+	}
+
+	return query_resolvers.GenerateEmoteResolver(ctx, emote, nil, field.Children)
+}
+
+// Change the favorite count of an emote, updating the emote with its new state
+func updateFavoriteCount(ctx context.Context, emote *datastructure.Emote, delta int32) error {
+	after := options.After
+	doc := mongo.Database.Collection("emotes").FindOneAndUpdate(ctx, bson.M{
+		"_id": emote.ID,
+	}, bson.M{
+		"$inc": bson.M{"favorite_count": delta},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return resolvers.ErrUnknownEmote
+		}
+		log.Errorf("mongo, err=%v", err)
+		return resolvers.ErrInternalServer
+	}
+
+	return nil
+}
@@ -0,0 +1,299 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/discord"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//
+// Mutate Emote - Request Global
+//
+func (*MutationResolver) RequestGlobalEmote(ctx context.Context, args struct {
+	EmoteID string
+	Reason  *string
+}) (*query_resolvers.EmoteResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.EmoteID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	emote := &datastructure.Emote{}
+	if err := mongo.Database.Collection("emotes").FindOne(ctx, bson.M{
+		"_id":    id,
+		"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
+	}).Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmote
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteEdit, emote); err != nil {
+		return nil, err
+	}
+	if emote.Status == datastructure.EmoteStatusPending {
+		return nil, resolvers.ErrEmoteAlreadyPending
+	}
+	if utils.BitField.HasBits(int64(emote.Visibility), int64(datastructure.EmoteVisibilityGlobal)) {
+		return nil, resolvers.ErrEmoteAlreadyGlobal
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	// Move the emote into the review queue
+	after := options.After
+	doc := mongo.Database.Collection("emotes").FindOneAndUpdate(ctx, bson.M{
+		"_id":    id,
+		"status": datastructure.EmoteStatusLive,
+	}, bson.M{
+		"$set": bson.M{
+			"status": datastructure.EmoteStatusPending,
+			"global_request": &datastructure.EmoteGlobalRequest{
+				RequestedByID: usr.ID,
+				RequestedAt:   time.Now(),
+				Reason:        args.Reason,
+			},
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrEmoteAlreadyPending
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeEmoteGlobalRequest,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &id, Type: "emotes"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "status", OldValue: datastructure.EmoteStatusLive, NewValue: datastructure.EmoteStatusPending},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	go discord.SendEmoteGlobalRequest(*emote, *usr, args.Reason)
+
+	return query_resolvers.GenerateEmoteResolver(ctx, emote, nil, field.Children)
+}
+
+//
+// Mutate Emote - Approve Global
+//
+func (*MutationResolver) ApproveGlobalEmote(ctx context.Context, args struct {
+	EmoteID string
+	Reason  string
+	SetID   *string
+}) (*query_resolvers.EmoteResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	if args.Reason == "" {
+		return nil, resolvers.ErrNoReason
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.EmoteID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	// While global emote sets exist, the global emotes are the union of the active sets
+	// An approved emote must then be added to a set, or it would be unflagged again
+	var set *datastructure.GlobalEmoteSet
+	if args.SetID != nil {
+		setID, err := primitive.ObjectIDFromHex(*args.SetID)
+		if err != nil {
+			return nil, resolvers.ErrUnknownEmoteSet
+		}
+		if set, err = actions.GetGlobalEmoteSet(ctx, setID); err != nil {
+			return nil, err
+		}
+	} else {
+		exist, err := actions.GlobalEmoteSetsExist(ctx)
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+		if exist {
+			return nil, resolvers.ErrGlobalEmoteSetRequired
+		}
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":                         datastructure.EmoteStatusLive,
+			"global_request.approved":        true,
+			"global_request.decided_by_id":   usr.ID,
+			"global_request.decided_at":      time.Now(),
+			"global_request.decision_reason": args.Reason,
+		},
+	}
+	if set == nil {
+		update["$bit"] = bson.M{"visibility": bson.M{"or": datastructure.EmoteVisibilityGlobal}}
+	}
+
+	emote, err := decideGlobalEmoteRequest(ctx, id, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if set != nil {
+		if _, err := mongo.Database.Collection("global_emote_sets").UpdateOne(ctx, bson.M{
+			"_id": set.ID,
+		}, bson.M{
+			"$addToSet": bson.M{"emotes": id},
+		}); err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+		if err := actions.SyncGlobalEmotes(ctx); err != nil {
+			log.Errorf("global emote sets, err=%v", err)
+		}
+	}
+
+	changes := []*datastructure.AuditLogChange{
+		{Key: "status", OldValue: datastructure.EmoteStatusPending, NewValue: datastructure.EmoteStatusLive},
+	}
+	if set != nil {
+		changes = append(changes, &datastructure.AuditLogChange{Key: "global_emote_set", OldValue: nil, NewValue: set.ID})
+	}
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeEmoteGlobalApprove,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &id, Type: "emotes"},
+		Changes:   changes,
+		Reason:    &args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	go discord.SendEmoteGlobalDecision(*emote, *usr, true, args.Reason)
+	actions.Notify(ctx, emote.OwnerID, fmt.Sprintf("Your emote %s has been approved for global status: %s", emote.Name, args.Reason), &datastructure.Target{ID: &id, Type: "emotes"})
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	return query_resolvers.GenerateEmoteResolver(ctx, emote, nil, field.Children)
+}
+
+//
+// Mutate Emote - Deny Global
+//
+func (*MutationResolver) DenyGlobalEmote(ctx context.Context, args struct {
+	EmoteID string
+	Reason  string
+}) (*query_resolvers.EmoteResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	if args.Reason == "" {
+		return nil, resolvers.ErrNoReason
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.EmoteID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmote
+	}
+
+	emote, err := decideGlobalEmoteRequest(ctx, id, bson.M{
+		"$set": bson.M{
+			"status":                         datastructure.EmoteStatusLive,
+			"global_request.approved":        false,
+			"global_request.decided_by_id":   usr.ID,
+			"global_request.decided_at":      time.Now(),
+			"global_request.decision_reason": args.Reason,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeEmoteGlobalDeny,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &id, Type: "emotes"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "status", OldValue: datastructure.EmoteStatusPending, NewValue: datastructure.EmoteStatusLive},
+		},
+		Reason: &args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	go discord.SendEmoteGlobalDecision(*emote, *usr, false, args.Reason)
+	actions.Notify(ctx, emote.OwnerID, fmt.Sprintf("Your emote %s has been denied global status: %s", emote.Name, args.Reason), &datastructure.Target{ID: &id, Type: "emotes"})
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	return query_resolvers.GenerateEmoteResolver(ctx, emote, nil, field.Children)
+}
+
+// Take an emote out of the review queue
+func decideGlobalEmoteRequest(ctx context.Context, id primitive.ObjectID, update bson.M) (*datastructure.Emote, error) {
+	emote := &datastructure.Emote{}
+	after := options.After
+	doc := mongo.Database.Collection("emotes").FindOneAndUpdate(ctx, bson.M{
+		"_id":    id,
+		"status": datastructure.EmoteStatusPending,
+	}, update, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(emote); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrEmoteNotPending
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return emote, nil
+}
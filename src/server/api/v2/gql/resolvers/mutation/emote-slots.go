@@ -0,0 +1,193 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//
+// GRANT EMOTE SLOTS
+//
+func (*MutationResolver) GrantEmoteSlots(ctx context.Context, args struct {
+	UserID   string
+	Amount   int32
+	ExpireAt *string
+	Reason   *string
+}) (*query_resolvers.UserResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.UserID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownUser
+	}
+	if err := authorizeUserManage(ctx, usr, id); err != nil {
+		return nil, err
+	}
+
+	if args.Amount <= 0 {
+		return nil, resolvers.ErrInvalidAmount
+	}
+
+	grant := &datastructure.EmoteSlotGrant{
+		ID:        primitive.NewObjectID(),
+		Amount:    args.Amount,
+		GrantedBy: usr.ID,
+	}
+	if args.ExpireAt != nil {
+		expireAt, err := time.Parse(time.RFC3339, *args.ExpireAt)
+		if err != nil {
+			return nil, resolvers.ErrInvalidUpdate
+		}
+		grant.ExpireAt = &expireAt
+	}
+	if args.Reason != nil {
+		grant.Reason = *args.Reason
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	user := &datastructure.User{}
+	after := options.After
+	doc := mongo.Database.Collection("users").FindOneAndUpdate(ctx, bson.M{
+		"_id": id,
+	}, bson.M{
+		"$push": bson.M{
+			"emote_slot_grants": grant,
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownUser
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserEmoteSlotGrant,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &id, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "emote_slot_grants", OldValue: nil, NewValue: grant},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return query_resolvers.GenerateUserResolver(ctx, user, &id, field.Children)
+}
+
+//
+// REVOKE EMOTE SLOT GRANT
+//
+func (*MutationResolver) RevokeEmoteSlotGrant(ctx context.Context, args struct {
+	UserID  string
+	GrantID string
+	Reason  *string
+}) (*query_resolvers.UserResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.UserID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownUser
+	}
+	if err := authorizeUserManage(ctx, usr, id); err != nil {
+		return nil, err
+	}
+
+	grantID, err := primitive.ObjectIDFromHex(args.GrantID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownGrant
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	user := &datastructure.User{}
+	before := options.Before
+	doc := mongo.Database.Collection("users").FindOneAndUpdate(ctx, bson.M{
+		"_id":                  id,
+		"emote_slot_grants.id": grantID,
+	}, bson.M{
+		"$pull": bson.M{
+			"emote_slot_grants": bson.M{"id": grantID},
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &before,
+	})
+	if err := doc.Decode(user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownGrant
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// Remove the revoked grant from the returned user
+	var revoked *datastructure.EmoteSlotGrant
+	grants := []*datastructure.EmoteSlotGrant{}
+	for _, g := range user.EmoteSlotGrants {
+		if g.ID == grantID {
+			revoked = g
+			continue
+		}
+		grants = append(grants, g)
+	}
+	user.EmoteSlotGrants = grants
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeUserEmoteSlotRevoke,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &id, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "emote_slot_grants", OldValue: revoked, NewValue: nil},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return query_resolvers.GenerateUserResolver(ctx, user, &id, field.Children)
+}
+
+// Verify that the actor may manage a user
+func authorizeUserManage(ctx context.Context, usr *datastructure.User, id primitive.ObjectID) error {
+	user := &datastructure.User{}
+	if err := mongo.Database.Collection("users").FindOne(ctx, bson.M{"_id": id}).Decode(user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return resolvers.ErrUnknownUser
+		}
+		log.Errorf("mongo, err=%v", err)
+		return resolvers.ErrInternalServer
+	}
+
+	return authorization.Authorize(ctx, usr, authorization.UserManage, user)
+}
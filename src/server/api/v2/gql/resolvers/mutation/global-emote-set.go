@@ -0,0 +1,302 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type globalEmoteSetInput struct {
+	Name     *string   `json:"name"`
+	EmoteIDs *[]string `json:"emote_ids"`
+	StartAt  *string   `json:"start_at"` // RFC3339, an empty string removes the start time
+	EndAt    *string   `json:"end_at"`   // RFC3339, an empty string removes the end time
+}
+
+//
+// Mutate Global Emote Set - Create
+//
+func (*MutationResolver) CreateGlobalEmoteSet(ctx context.Context, args struct {
+	Set    globalEmoteSetInput
+	Reason *string
+}) (*query_resolvers.GlobalEmoteSetResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	if args.Set.Name == nil {
+		return nil, resolvers.ErrInvalidName
+	}
+
+	set := &datastructure.GlobalEmoteSet{
+		EmoteIDs:    []primitive.ObjectID{},
+		CreatedByID: usr.ID,
+		CreatedAt:   time.Now(),
+	}
+	if err := applyGlobalEmoteSetInput(ctx, set, args.Set); err != nil {
+		return nil, err
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	res, err := mongo.Database.Collection("global_emote_sets").InsertOne(ctx, set)
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	set.ID = res.InsertedID.(primitive.ObjectID)
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeGlobalEmoteSetCreate,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &set.ID, Type: "global_emote_sets"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "global_emote_set", OldValue: nil, NewValue: set},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	if err := actions.SyncGlobalEmotes(ctx); err != nil {
+		log.Errorf("global emote sets, err=%v", err)
+	}
+
+	return query_resolvers.GenerateGlobalEmoteSetResolver(ctx, set, field.Children)
+}
+
+//
+// Mutate Global Emote Set - Edit
+//
+func (*MutationResolver) EditGlobalEmoteSet(ctx context.Context, args struct {
+	ID     string
+	Set    globalEmoteSetInput
+	Reason *string
+}) (*query_resolvers.GlobalEmoteSetResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmoteSet
+	}
+
+	set, err := actions.GetGlobalEmoteSet(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	old := *set
+
+	if err := applyGlobalEmoteSetInput(ctx, set, args.Set); err != nil {
+		return nil, err
+	}
+
+	logChanges := []*datastructure.AuditLogChange{}
+	if old.Name != set.Name {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "name", OldValue: old.Name, NewValue: set.Name})
+	}
+	if args.Set.EmoteIDs != nil {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "emotes", OldValue: old.EmoteIDs, NewValue: set.EmoteIDs})
+	}
+	if args.Set.StartAt != nil {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "start_at", OldValue: old.StartAt, NewValue: set.StartAt})
+	}
+	if args.Set.EndAt != nil {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "end_at", OldValue: old.EndAt, NewValue: set.EndAt})
+	}
+	if len(logChanges) == 0 {
+		return nil, resolvers.ErrInvalidUpdate
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	after := options.After
+	doc := mongo.Database.Collection("global_emote_sets").FindOneAndUpdate(ctx, bson.M{
+		"_id": id,
+	}, bson.M{
+		"$set": bson.M{
+			"name":     set.Name,
+			"emotes":   set.EmoteIDs,
+			"start_at": set.StartAt,
+			"end_at":   set.EndAt,
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(set); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmoteSet
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeGlobalEmoteSetEdit,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &id, Type: "global_emote_sets"},
+		Changes:   logChanges,
+		Reason:    args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	if err := actions.SyncGlobalEmotes(ctx); err != nil {
+		log.Errorf("global emote sets, err=%v", err)
+	}
+
+	return query_resolvers.GenerateGlobalEmoteSetResolver(ctx, set, field.Children)
+}
+
+//
+// Mutate Global Emote Set - Delete
+//
+func (*MutationResolver) DeleteGlobalEmoteSet(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(args.ID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownEmoteSet
+	}
+
+	set := &datastructure.GlobalEmoteSet{}
+	if err := mongo.Database.Collection("global_emote_sets").FindOneAndDelete(ctx, bson.M{"_id": id}).Decode(set); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownEmoteSet
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeGlobalEmoteSetDelete,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &id, Type: "global_emote_sets"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "global_emote_set", OldValue: set, NewValue: nil},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	if err := actions.SyncGlobalEmotes(ctx); err != nil {
+		log.Errorf("global emote sets, err=%v", err)
+	}
+
+	return &response{
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+// Validate a global emote set input and apply it to the set
+func applyGlobalEmoteSetInput(ctx context.Context, set *datastructure.GlobalEmoteSet, input globalEmoteSetInput) error {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if len(name) == 0 || len(name) > 64 {
+			return resolvers.ErrInvalidName
+		}
+		set.Name = name
+	}
+
+	if input.EmoteIDs != nil {
+		ids := []primitive.ObjectID{}
+		seen := map[primitive.ObjectID]bool{}
+		for _, s := range *input.EmoteIDs {
+			id, err := primitive.ObjectIDFromHex(s)
+			if err != nil {
+				return resolvers.ErrUnknownEmote
+			}
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			ids = append(ids, id)
+		}
+
+		// Every emote of the set must exist and be live
+		if len(ids) > 0 {
+			count, err := mongo.Database.Collection("emotes").CountDocuments(ctx, bson.M{
+				"_id":    bson.M{"$in": ids},
+				"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
+			})
+			if err != nil {
+				log.Errorf("mongo, err=%v", err)
+				return resolvers.ErrInternalServer
+			}
+			if int(count) != len(ids) {
+				return resolvers.ErrUnknownEmote
+			}
+		}
+		set.EmoteIDs = ids
+	}
+
+	for _, v := range []struct {
+		in  *string
+		out **time.Time
+	}{{input.StartAt, &set.StartAt}, {input.EndAt, &set.EndAt}} {
+		if v.in == nil {
+			continue
+		}
+		if *v.in == "" {
+			*v.out = nil
+			continue
+		}
+
+		t, err := time.Parse(time.RFC3339, *v.in)
+		if err != nil {
+			return resolvers.ErrInvalidUpdate
+		}
+		*v.out = &t
+	}
+
+	if set.StartAt != nil && set.EndAt != nil && !set.EndAt.After(*set.StartAt) {
+		return resolvers.ErrInvalidUpdate
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package mutation_resolvers
+
+import (
+	"context"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+//
+// Mutate Notifications - Mark Read
+//
+func (*MutationResolver) MarkNotificationsRead(ctx context.Context, args struct {
+	IDs *[]string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.AccountEdit, nil); err != nil {
+		return nil, err
+	}
+
+	// Mark all notifications as read if no IDs are specified
+	query := bson.M{"user_id": usr.ID, "read": false}
+	if args.IDs != nil {
+		query["_id"] = bson.M{"$in": mongo.HexIDSliceToObjectID(*args.IDs)}
+	}
+
+	if _, err := mongo.Database.Collection("notifications").UpdateMany(ctx, query, bson.M{
+		"$set": bson.M{"read": true},
+	}); err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return &response{
+		Status:  200,
+		Message: "success",
+	}, nil
+}
@@ -3,9 +3,10 @@ package mutation_resolvers
 import (
 	"context"
 
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
-	"github.com/SevenTV/ServerGo/src/redis"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	"github.com/SevenTV/ServerGo/src/utils"
 	log "github.com/sirupsen/logrus"
@@ -33,7 +34,7 @@ func (*MutationResolver) ReportEmote(ctx context.Context, args struct {
 
 	res := mongo.Database.Collection("emotes").FindOne(ctx, bson.M{
 		"_id":    id,
-		"status": datastructure.EmoteStatusLive,
+		"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
 	})
 
 	emote := &datastructure.Emote{}
@@ -72,7 +73,7 @@ func (*MutationResolver) ReportEmote(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
+	err = audit.Insert(ctx, &datastructure.AuditLog{
 		Type:      datastructure.AuditLogTypeReport,
 		CreatedBy: usr.ID,
 		Target:    &datastructure.Target{ID: &id, Type: "emotes"},
@@ -110,13 +111,11 @@ func (*MutationResolver) ReportUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrYourself
 	}
 
-	_, err = redis.Client.HGet(ctx, "user:bans", id.Hex()).Result()
-	if err != nil && err != redis.ErrNil {
-		log.Errorf("redis, err=%v", err)
-		return nil, resolvers.ErrInternalServer
+	banned, err := authorization.IsBanned(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-
-	if err == nil {
+	if banned {
 		return nil, resolvers.ErrUserBanned
 	}
 
@@ -161,7 +160,7 @@ func (*MutationResolver) ReportUser(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	_, err = mongo.Database.Collection("audit").InsertOne(ctx, &datastructure.AuditLog{
+	err = audit.Insert(ctx, &datastructure.AuditLog{
 		Type:      datastructure.AuditLogTypeReport,
 		CreatedBy: usr.ID,
 		Target:    &datastructure.Target{ID: &id, Type: "emotes"},
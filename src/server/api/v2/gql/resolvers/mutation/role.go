@@ -2,19 +2,542 @@ package mutation_resolvers
 
 import (
 	"context"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	query_resolvers "github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers/query"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type roleInput struct {
-	ID       string      `json:"id"`
-	Name     string      `json:"name"`
-	Color    *int32      `json:"color"`
-	Position *int32      `json:"position"`
-	Allowed  *int64      `json:"allowed"`
-	Denied   *complex128 `json:"denied"`
+	Name              *string `json:"name"`
+	Color             *int32  `json:"color"`
+	Position          *int32  `json:"position"`
+	Allowed           *string `json:"allowed"` // A permission bitset, as a string since it doesn't fit in a GraphQL Int
+	Denied            *string `json:"denied"`
+	ChannelEmoteSlots *int32  `json:"channel_emote_slots"`
 }
 
-func (r *MutationResolver) CreateRole(ctx context.Context, args roleInput) (*query_resolvers.RoleResolver, error) {
-	return nil, nil
+//
+// Mutate Role - Create
+//
+func (*MutationResolver) CreateRole(ctx context.Context, args struct {
+	Role   roleInput
+	Reason *string
+}) (*query_resolvers.RoleResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if args.Role.Name == nil {
+		return nil, resolvers.ErrInvalidName
+	}
+	if args.Role.Position == nil {
+		return nil, resolvers.ErrInvalidPosition
+	}
+
+	role := &datastructure.Role{ID: primitive.NewObjectID()}
+	if err := applyRoleInput(usr, role, args.Role); err != nil {
+		return nil, err
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.RoleManage, role); err != nil {
+		return nil, err
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	if _, err := mongo.Database.Collection("roles").InsertOne(ctx, role); err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err := audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeRoleCreate,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &role.ID, Type: "roles"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "role", OldValue: nil, NewValue: role},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return query_resolvers.GenerateRoleResolver(ctx, role, nil, field.Children)
+}
+
+//
+// Mutate Role - Edit
+//
+func (*MutationResolver) EditRole(ctx context.Context, args struct {
+	ID     string
+	Role   roleInput
+	Reason *string
+}) (*query_resolvers.RoleResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	role, err := findRole(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.RoleManage, role); err != nil {
+		return nil, err
+	}
+	old := *role
+
+	// The role must also be below the actor at its new position
+	if err := applyRoleInput(usr, role, args.Role); err != nil {
+		return nil, err
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.RoleManage, role); err != nil {
+		return nil, err
+	}
+
+	logChanges := []*datastructure.AuditLogChange{}
+	if old.Name != role.Name {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "name", OldValue: old.Name, NewValue: role.Name})
+	}
+	if old.Color != role.Color {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "color", OldValue: old.Color, NewValue: role.Color})
+	}
+	if old.Position != role.Position {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "position", OldValue: old.Position, NewValue: role.Position})
+	}
+	if old.Allowed != role.Allowed {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "allowed", OldValue: old.Allowed, NewValue: role.Allowed})
+	}
+	if old.Denied != role.Denied {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "denied", OldValue: old.Denied, NewValue: role.Denied})
+	}
+	if old.ChannelEmoteSlots != role.ChannelEmoteSlots {
+		logChanges = append(logChanges, &datastructure.AuditLogChange{Key: "channel_emote_slots", OldValue: old.ChannelEmoteSlots, NewValue: role.ChannelEmoteSlots})
+	}
+	if len(logChanges) == 0 {
+		return nil, resolvers.ErrInvalidUpdate
+	}
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	after := options.After
+	doc := mongo.Database.Collection("roles").FindOneAndUpdate(ctx, bson.M{
+		"_id": role.ID,
+	}, bson.M{
+		"$set": bson.M{
+			"name":                role.Name,
+			"color":               role.Color,
+			"position":            role.Position,
+			"allowed":             role.Allowed,
+			"denied":              role.Denied,
+			"channel_emote_slots": role.ChannelEmoteSlots,
+		},
+	}, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(role); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownRole
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeRoleEdit,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &role.ID, Type: "roles"},
+		Changes:   logChanges,
+		Reason:    args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return query_resolvers.GenerateRoleResolver(ctx, role, nil, field.Children)
+}
+
+//
+// Mutate Role - Delete
+//
+func (*MutationResolver) DeleteRole(ctx context.Context, args struct {
+	ID     string
+	Reason *string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	role, err := findRole(ctx, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.RoleManage, role); err != nil {
+		return nil, err
+	}
+
+	if _, err := mongo.Database.Collection("roles").DeleteOne(ctx, bson.M{"_id": role.ID}); err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// Members of the role fall back to the default role
+	if _, err := mongo.Database.Collection("users").UpdateMany(ctx, bson.M{
+		"role": role.ID,
+	}, bson.M{
+		"$unset": bson.M{"role": ""},
+	}); err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeRoleDelete,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &role.ID, Type: "roles"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "role", OldValue: role, NewValue: nil},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return &response{
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+//
+// Mutate Role - Reorder
+//
+// The roles are given from highest to lowest, and swap the positions they hold between them
+// This keeps every role within the range of positions the actor may manage
+//
+func (*MutationResolver) ReorderRoles(ctx context.Context, args struct {
+	IDs    []string
+	Reason *string
+}) ([]*query_resolvers.RoleResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	ids := []primitive.ObjectID{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, s := range args.IDs {
+		id, err := primitive.ObjectIDFromHex(s)
+		if err != nil {
+			return nil, resolvers.ErrUnknownRole
+		}
+		if seen[id] {
+			return nil, resolvers.ErrInvalidUpdate
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) < 2 {
+		return nil, resolvers.ErrInvalidUpdate
+	}
+
+	roles := []*datastructure.Role{}
+	cur, err := mongo.Database.Collection("roles").Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err == nil {
+		err = cur.All(ctx, &roles)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	if len(roles) != len(ids) {
+		return nil, resolvers.ErrUnknownRole
+	}
+
+	byID := map[primitive.ObjectID]*datastructure.Role{}
+	positions := make([]int32, len(roles))
+	for i, r := range roles {
+		if err := authorization.Authorize(ctx, usr, authorization.RoleManage, r); err != nil {
+			return nil, err
+		}
+		byID[r.ID] = r
+		positions[i] = r.Position
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		return positions[i] > positions[j]
+	})
+
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	oldPositions := bson.M{}
+	newPositions := bson.M{}
+	models := []mongo.WriteModel{}
+	result := make([]*query_resolvers.RoleResolver, len(ids))
+	for i, id := range ids {
+		role := byID[id]
+		if role.Position != positions[i] {
+			oldPositions[id.Hex()] = role.Position
+			newPositions[id.Hex()] = positions[i]
+			models = append(models, mongo.NewUpdateOneModel().SetFilter(bson.M{"_id": id}).SetUpdate(bson.M{
+				"$set": bson.M{"position": positions[i]},
+			}))
+			role.Position = positions[i]
+		}
+
+		if result[i], err = query_resolvers.GenerateRoleResolver(ctx, role, nil, field.Children); err != nil {
+			return nil, err
+		}
+	}
+	if len(models) == 0 {
+		return result, nil
+	}
+
+	if _, err := mongo.Database.Collection("roles").BulkWrite(ctx, models); err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err = audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeRoleReorder,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{Type: "roles"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "positions", OldValue: oldPositions, NewValue: newPositions},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return result, nil
+}
+
+//
+// Mutate User - Assign Role
+//
+func (*MutationResolver) AssignRole(ctx context.Context, args struct {
+	UserID string
+	RoleID string
+	Reason *string
+}) (*query_resolvers.UserResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	role, err := findRole(ctx, args.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.RoleManage, role); err != nil {
+		return nil, err
+	}
+	// Members of the role get its permissions, so the actor must be able to grant them
+	if !authorization.CanGrant(usr, role.Allowed) {
+		return nil, resolvers.ErrAccessDenied
+	}
+
+	user, err := findRoleMember(ctx, usr, args.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.RoleID != nil && *user.RoleID == role.ID {
+		return nil, resolvers.ErrInvalidUpdate
+	}
+
+	return setUserRole(ctx, usr, user, &role.ID, args.Reason)
+}
+
+//
+// Mutate User - Unassign Role
+//
+func (*MutationResolver) UnassignRole(ctx context.Context, args struct {
+	UserID string
+	Reason *string
+}) (*query_resolvers.UserResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	user, err := findRoleMember(ctx, usr, args.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if user.RoleID == nil {
+		return nil, resolvers.ErrNoRole
+	}
+
+	return setUserRole(ctx, usr, user, nil, args.Reason)
+}
+
+// Get a role by its hex id
+func findRole(ctx context.Context, hexID string) (*datastructure.Role, error) {
+	id, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownRole
+	}
+
+	role := &datastructure.Role{}
+	if err := mongo.Database.Collection("roles").FindOne(ctx, bson.M{"_id": id}).Decode(role); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownRole
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return role, nil
+}
+
+// Get a user whose role the actor wants to change
+func findRoleMember(ctx context.Context, usr *datastructure.User, hexID string) (*datastructure.User, error) {
+	id, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownUser
+	}
+	if id == usr.ID {
+		return nil, resolvers.ErrYourself
+	}
+
+	user := &datastructure.User{}
+	if err := mongo.Database.Collection("users").FindOne(ctx, bson.M{"_id": id}).Decode(user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownUser
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// The actor must be above the user's current role
+	role := datastructure.GetRole(ctx, user.RoleID)
+	if err := authorization.Authorize(ctx, usr, authorization.RoleManage, &role); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// Set or remove the role of a user
+func setUserRole(ctx context.Context, usr *datastructure.User, user *datastructure.User, roleID *primitive.ObjectID, reason *string) (*query_resolvers.UserResolver, error) {
+	field, failed := query_resolvers.GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	logType := datastructure.AuditLogTypeUserRoleAssign
+	update := bson.M{"$set": bson.M{"role": roleID}}
+	if roleID == nil {
+		logType = datastructure.AuditLogTypeUserRoleUnassign
+		update = bson.M{"$unset": bson.M{"role": ""}}
+	}
+
+	// The role must not have changed since the hierarchy was checked
+	filter := bson.M{"_id": user.ID, "role": user.RoleID}
+	if user.RoleID == nil {
+		filter["role"] = bson.M{"$exists": false}
+	}
+
+	oldRoleID := user.RoleID
+	after := options.After
+	doc := mongo.Database.Collection("users").FindOneAndUpdate(ctx, filter, update, &options.FindOneAndUpdateOptions{
+		ReturnDocument: &after,
+	})
+	if err := doc.Decode(user); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrInvalidUpdate
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err := audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      logType,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{ID: &user.ID, Type: "users"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: "role", OldValue: oldRoleID, NewValue: roleID},
+		},
+		Reason: reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return query_resolvers.GenerateUserResolver(ctx, user, &user.ID, field.Children)
+}
+
+// Validate a role input and apply it to the role
+func applyRoleInput(usr *datastructure.User, role *datastructure.Role, input roleInput) error {
+	if input.Name != nil {
+		name := strings.TrimSpace(*input.Name)
+		if len(name) == 0 || len(name) > 32 {
+			return resolvers.ErrInvalidName
+		}
+		role.Name = name
+	}
+
+	if input.Color != nil {
+		role.Color = *input.Color
+	}
+
+	if input.Position != nil {
+		if *input.Position < 0 {
+			return resolvers.ErrInvalidPosition
+		}
+		role.Position = *input.Position
+	}
+
+	if input.Allowed != nil {
+		allowed, err := strconv.ParseInt(*input.Allowed, 10, 64)
+		if err != nil || allowed < 0 || allowed > datastructure.RolePermissionAll {
+			return resolvers.ErrInvalidPermissions
+		}
+		// Only the permissions newly allowed are checked, so that a role can still be edited after the actor lost some
+		if !authorization.CanGrant(usr, utils.BitField.RemoveBits(allowed, role.Allowed)) {
+			return resolvers.ErrAccessDenied
+		}
+		role.Allowed = allowed
+	}
+
+	if input.Denied != nil {
+		denied, err := strconv.ParseInt(*input.Denied, 10, 64)
+		if err != nil || denied < 0 || denied > datastructure.RolePermissionAll {
+			return resolvers.ErrInvalidPermissions
+		}
+		role.Denied = denied
+	}
+
+	if input.ChannelEmoteSlots != nil {
+		if *input.ChannelEmoteSlots < 0 {
+			return resolvers.ErrInvalidAmount
+		}
+		role.ChannelEmoteSlots = *input.ChannelEmoteSlots
+	}
+
+	return nil
 }
@@ -0,0 +1,155 @@
+package mutation_resolvers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/SevenTV/ServerGo/src/validation"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//
+// Mutate Tag Synonym - Set
+//
+func (*MutationResolver) SetTagSynonym(ctx context.Context, args struct {
+	Synonym string
+	Tag     string
+	Reason  *string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	synonym := strings.ToLower(args.Synonym)
+	tag := strings.ToLower(args.Tag)
+	if !validation.ValidateEmoteTag(utils.S2B(synonym)) || !validation.ValidateEmoteTag(utils.S2B(tag)) || synonym == tag {
+		return nil, resolvers.ErrInvalidTag
+	}
+
+	// Synonyms cannot be chained, the tag must not be a synonym itself
+	if err := mongo.Database.Collection("tag_synonyms").FindOne(ctx, bson.M{"_id": tag}).Err(); err != mongo.ErrNoDocuments {
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+		return nil, resolvers.ErrInvalidTag
+	}
+
+	old := &datastructure.TagSynonym{}
+	if err := mongo.Database.Collection("tag_synonyms").FindOne(ctx, bson.M{"_id": synonym}).Decode(old); err != nil {
+		if err != mongo.ErrNoDocuments {
+			log.Errorf("mongo, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+		old = nil
+	}
+
+	upsert := true
+	if _, err := mongo.Database.Collection("tag_synonyms").UpdateOne(ctx, bson.M{
+		"_id": synonym,
+	}, bson.M{
+		"$set": bson.M{
+			"tag":           tag,
+			"created_by_id": usr.ID,
+			"created_at":    time.Now(),
+		},
+	}, &options.UpdateOptions{Upsert: &upsert}); err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// Synonyms of the new synonym now point to its tag
+	if _, err := mongo.Database.Collection("tag_synonyms").UpdateMany(ctx, bson.M{
+		"tag": synonym,
+	}, bson.M{
+		"$set": bson.M{"tag": tag},
+	}); err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	if err := actions.ApplyTagSynonym(ctx, synonym, tag); err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	var oldTag interface{}
+	if old != nil {
+		oldTag = old.Tag
+	}
+	err := audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeTagSynonymSet,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{Type: "tag_synonyms"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: synonym, OldValue: oldTag, NewValue: tag},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return &response{
+		Status:  200,
+		Message: "success",
+	}, nil
+}
+
+//
+// Mutate Tag Synonym - Remove
+//
+func (*MutationResolver) RemoveTagSynonym(ctx context.Context, args struct {
+	Synonym string
+	Reason  *string
+}) (*response, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	synonym := strings.ToLower(args.Synonym)
+	old := &datastructure.TagSynonym{}
+	if err := mongo.Database.Collection("tag_synonyms").FindOneAndDelete(ctx, bson.M{"_id": synonym}).Decode(old); err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, resolvers.ErrUnknownTagSynonym
+		}
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	err := audit.Insert(ctx, &datastructure.AuditLog{
+		Type:      datastructure.AuditLogTypeTagSynonymRemove,
+		CreatedBy: usr.ID,
+		Target:    &datastructure.Target{Type: "tag_synonyms"},
+		Changes: []*datastructure.AuditLogChange{
+			{Key: synonym, OldValue: old.Tag, NewValue: nil},
+		},
+		Reason: args.Reason,
+	})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+	}
+
+	return &response{
+		Status:  200,
+		Message: "success",
+	}, nil
+}
@@ -0,0 +1,88 @@
+package query_resolvers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AccessTokenResolver struct {
+	ctx context.Context
+	v   *datastructure.AccessToken
+}
+
+func GenerateAccessTokenResolver(ctx context.Context, token *datastructure.AccessToken) *AccessTokenResolver {
+	return &AccessTokenResolver{
+		ctx: ctx,
+		v:   token,
+	}
+}
+
+// Get the personal access tokens of this user, newest first
+func (r *UserResolver) AccessTokens() ([]*AccessTokenResolver, error) {
+	usr, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if err := authorization.Authorize(r.ctx, usr, authorization.UserPrivateView, r.v); err != nil {
+		return nil, err
+	}
+
+	tokens := []*datastructure.AccessToken{}
+	cur, err := mongo.Database.Collection("access_tokens").Find(r.ctx, bson.M{
+		"user_id":   r.v.ID,
+		"expire_at": bson.M{"$gt": time.Now()},
+	}, options.Find().SetSort(bson.M{"_id": -1}).SetLimit(resolvers.QueryLimit))
+	if err == nil {
+		err = cur.All(r.ctx, &tokens)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*AccessTokenResolver, len(tokens))
+	for i, t := range tokens {
+		result[i] = GenerateAccessTokenResolver(r.ctx, t)
+	}
+
+	return result, nil
+}
+
+func (r *AccessTokenResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *AccessTokenResolver) Name() string {
+	return r.v.Name
+}
+
+func (r *AccessTokenResolver) Permissions() string {
+	return fmt.Sprint(r.v.Permissions)
+}
+
+func (r *AccessTokenResolver) Scopes() int32 {
+	return int32(r.v.Scopes)
+}
+
+func (r *AccessTokenResolver) ExpiresAt() string {
+	return r.v.ExpireAt.Format(time.RFC3339)
+}
+
+func (r *AccessTokenResolver) LastUsedAt() *string {
+	if r.v.LastUsedAt == nil {
+		return nil
+	}
+	s := r.v.LastUsedAt.Format(time.RFC3339)
+	return &s
+}
+
+func (r *AccessTokenResolver) CreatedAt() string {
+	return r.v.CreatedAt.Format(time.RFC3339)
+}
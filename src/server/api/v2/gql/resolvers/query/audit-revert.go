@@ -0,0 +1,64 @@
+package query_resolvers
+
+import (
+	"context"
+
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+)
+
+type AuditRevertResultResolver struct {
+	ctx context.Context
+	v   *actions.AuditRevertResult
+
+	fields map[string]*SelectedField
+}
+
+type auditRevertConflictResolver struct {
+	v *actions.AuditRevertConflict
+}
+
+func GenerateAuditRevertResultResolver(ctx context.Context, result *actions.AuditRevertResult, fields map[string]*SelectedField) *AuditRevertResultResolver {
+	return &AuditRevertResultResolver{
+		ctx:    ctx,
+		v:      result,
+		fields: fields,
+	}
+}
+
+func (r *AuditRevertResultResolver) Entry() (*auditResolver, error) {
+	return GenerateAuditResolver(r.ctx, r.v.Entry, childFields(r.fields, "entry"))
+}
+
+func (r *AuditRevertResultResolver) RevertID() *string {
+	if r.v.RevertID == nil {
+		return nil
+	}
+
+	id := r.v.RevertID.Hex()
+	return &id
+}
+
+func (r *AuditRevertResultResolver) Applied() []string {
+	if r.v.Applied == nil {
+		return []string{}
+	}
+
+	return r.v.Applied
+}
+
+func (r *AuditRevertResultResolver) Conflicts() []*auditRevertConflictResolver {
+	conflicts := make([]*auditRevertConflictResolver, len(r.v.Conflicts))
+	for i, c := range r.v.Conflicts {
+		conflicts[i] = &auditRevertConflictResolver{v: c}
+	}
+
+	return conflicts
+}
+
+func (c *auditRevertConflictResolver) Key() string {
+	return c.v.Key
+}
+
+func (c *auditRevertConflictResolver) Reason() string {
+	return c.v.Reason
+}
@@ -2,17 +2,330 @@ package query_resolvers
 
 import (
 	"context"
+	"time"
 
+	"github.com/SevenTV/ServerGo/src/audit"
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/search"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type auditResolver struct {
 	ctx context.Context
 	v   *datastructure.AuditLog
 
+	// Actors and targets loaded ahead of time for a whole page of entries
+	users  map[primitive.ObjectID]*datastructure.User
+	emotes map[primitive.ObjectID]*datastructure.Emote
+
 	fields map[string]*SelectedField
 }
 
+type AuditLogConnectionResolver struct {
+	ctx      context.Context
+	logs     []*datastructure.AuditLog
+	cursors  []string
+	nextPage bool
+	users    map[primitive.ObjectID]*datastructure.User
+	emotes   map[primitive.ObjectID]*datastructure.Emote
+
+	// The cursor of the next page when it doesn't start after the last entry
+	endCursor *string
+
+	fields map[string]*SelectedField
+}
+
+type auditEdgeResolver struct {
+	cursor string
+	node   *auditResolver
+}
+
+// Audit entries are listed newest first
+var auditLogSort = bson.D{{Key: "_id", Value: -1}}
+
+type auditLogsArgs struct {
+	After      *string
+	Limit      *int32
+	Types      *[]int32
+	ActorID    *string
+	TargetType *string
+	TargetID   *string
+	Since      *string
+	Until      *string
+}
+
+func (*QueryResolver) AuditLogs(ctx context.Context, args auditLogsArgs) (*AuditLogConnectionResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.AuditLogsView, nil); err != nil {
+		return nil, err
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	limit, err := connectionLimit(args.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parseAuditFilter(args)
+	if err != nil {
+		return nil, err
+	}
+
+	return findAuditLogs(ctx, f.Match(), args.After, limit, field.Children)
+}
+
+func (*QueryResolver) AuditArchive(ctx context.Context, args auditLogsArgs) (*AuditLogConnectionResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.Administrate, nil); err != nil {
+		return nil, err
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	limit, err := connectionLimit(args.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := parseAuditFilter(args)
+	if err != nil {
+		return nil, err
+	}
+
+	var after *primitive.ObjectID
+	if args.After != nil {
+		values, err := search.DecodeCursor(auditLogSort, *args.After)
+		if err != nil {
+			return nil, resolvers.ErrInvalidCursor
+		}
+		id, ok := values[0].(primitive.ObjectID)
+		if !ok {
+			return nil, resolvers.ErrInvalidCursor
+		}
+		after = &id
+	}
+
+	policy, err := audit.GetRetentionPolicy()
+	if err != nil {
+		log.Errorf("audit retention, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	logs, resume, err := audit.SearchArchive(ctx, policy, f, after, limit)
+	if err != nil {
+		if err == audit.ErrArchiveSearchTooWide {
+			return nil, err
+		}
+		log.Errorf("audit archive, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	conn, err := newAuditLogConnection(ctx, logs, limit, field.Children)
+	if err != nil {
+		return nil, err
+	}
+
+	// The search stopped early, the next page resumes where it did
+	if resume != nil {
+		c, err := search.EncodeCursor(auditLogSort, bson.A{*resume})
+		if err != nil {
+			log.Errorf("search, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+		conn.nextPage = true
+		conn.endCursor = &c
+	}
+
+	return conn, nil
+}
+
+// Read the filter arguments of an audit log query
+func parseAuditFilter(args auditLogsArgs) (audit.Filter, error) {
+	f := audit.Filter{TargetType: args.TargetType}
+	if args.Types != nil {
+		f.Types = *args.Types
+	}
+	if args.ActorID != nil {
+		id, err := primitive.ObjectIDFromHex(*args.ActorID)
+		if err != nil {
+			return f, resolvers.ErrInvalidFilter
+		}
+		f.ActorID = &id
+	}
+	if args.TargetID != nil {
+		id, err := primitive.ObjectIDFromHex(*args.TargetID)
+		if err != nil {
+			return f, resolvers.ErrInvalidFilter
+		}
+		f.TargetID = &id
+	}
+	if args.Since != nil {
+		t, err := time.Parse(time.RFC3339, *args.Since)
+		if err != nil {
+			return f, resolvers.ErrInvalidFilter
+		}
+		f.Since = &t
+	}
+	if args.Until != nil {
+		t, err := time.Parse(time.RFC3339, *args.Until)
+		if err != nil {
+			return f, resolvers.ErrInvalidFilter
+		}
+		f.Until = &t
+	}
+
+	return f, nil
+}
+
+// Get a page of the audit entries matching a query, newest first
+func findAuditLogs(ctx context.Context, match bson.M, after *string, limit int64, fields map[string]*SelectedField) (*AuditLogConnectionResolver, error) {
+	filter := match
+	if after != nil {
+		values, err := search.DecodeCursor(auditLogSort, *after)
+		if err != nil {
+			return nil, resolvers.ErrInvalidCursor
+		}
+		filter = bson.M{"$and": bson.A{match, search.KeysetFilter(auditLogSort, values)}}
+	}
+
+	logs := []*datastructure.AuditLog{}
+	cur, err := mongo.Database.Collection("audit").Find(ctx, filter, options.Find().SetSort(auditLogSort).SetLimit(limit+1))
+	if err == nil {
+		err = cur.All(ctx, &logs)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return newAuditLogConnection(ctx, logs, limit, fields)
+}
+
+// Create a connection from a page of audit entries
+// logs holds one more entry than the limit if there is a next page
+func newAuditLogConnection(ctx context.Context, logs []*datastructure.AuditLog, limit int64, fields map[string]*SelectedField) (*AuditLogConnectionResolver, error) {
+	var err error
+	nextPage := int64(len(logs)) > limit
+	if nextPage {
+		logs = logs[:limit]
+	}
+
+	cursors := make([]string, len(logs))
+	for i, l := range logs {
+		if cursors[i], err = search.EncodeCursor(auditLogSort, bson.A{l.ID}); err != nil {
+			log.Errorf("search, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+	}
+
+	users, emotes, err := loadAuditReferences(ctx, logs, childFields(fields, "edges", "node"))
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	return &AuditLogConnectionResolver{
+		ctx:      ctx,
+		logs:     logs,
+		cursors:  cursors,
+		nextPage: nextPage,
+		users:    users,
+		emotes:   emotes,
+		fields:   fields,
+	}, nil
+}
+
+// Fetch the actors and targets of a page of audit entries with one query per collection,
+// only loading what has been selected
+func loadAuditReferences(ctx context.Context, logs []*datastructure.AuditLog, fields map[string]*SelectedField) (map[primitive.ObjectID]*datastructure.User, map[primitive.ObjectID]*datastructure.Emote, error) {
+	targetFields := childFields(fields, "target")
+	_, wantActor := fields["action_user"]
+	_, wantUser := targetFields["user"]
+	_, wantEmote := targetFields["emote"]
+	_, wantAdded := fields["added_emotes"]
+	_, wantRemoved := fields["removed_emotes"]
+
+	userIDs := []primitive.ObjectID{}
+	emoteIDs := []primitive.ObjectID{}
+	for _, l := range logs {
+		if wantActor && !l.CreatedBy.IsZero() {
+			userIDs = append(userIDs, l.CreatedBy)
+		}
+		if wantAdded || wantRemoved {
+			if added, removed, ok := auditEmoteDiff(l); ok {
+				if wantAdded {
+					emoteIDs = append(emoteIDs, added...)
+				}
+				if wantRemoved {
+					emoteIDs = append(emoteIDs, removed...)
+				}
+			}
+		}
+		if l.Target == nil || l.Target.ID == nil {
+			continue
+		}
+		switch {
+		case wantUser && l.Target.Type == "users":
+			userIDs = append(userIDs, *l.Target.ID)
+		case wantEmote && l.Target.Type == "emotes":
+			emoteIDs = append(emoteIDs, *l.Target.ID)
+		}
+	}
+
+	users := map[primitive.ObjectID]*datastructure.User{}
+	if len(userIDs) > 0 {
+		found := []*datastructure.User{}
+		cur, err := mongo.Database.Collection("users").Find(ctx, bson.M{"_id": bson.M{"$in": userIDs}})
+		if err == nil {
+			err = cur.All(ctx, &found)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, u := range found {
+			users[u.ID] = u
+		}
+	}
+
+	emotes := map[primitive.ObjectID]*datastructure.Emote{}
+	if len(emoteIDs) > 0 {
+		found := []*datastructure.Emote{}
+		cur, err := mongo.Database.Collection("emotes").Find(ctx, bson.M{"_id": bson.M{"$in": emoteIDs}})
+		if err == nil {
+			err = cur.All(ctx, &found)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, e := range found {
+			emotes[e.ID] = e
+		}
+	}
+
+	return users, emotes, nil
+}
+
 func GenerateAuditResolver(ctx context.Context, audit *datastructure.AuditLog, fields map[string]*SelectedField) (*auditResolver, error) {
 	return &auditResolver{
 		ctx:    ctx,
@@ -21,6 +334,43 @@ func GenerateAuditResolver(ctx context.Context, audit *datastructure.AuditLog, f
 	}, nil
 }
 
+func (r *AuditLogConnectionResolver) Edges() []*auditEdgeResolver {
+	fields := childFields(r.fields, "edges", "node")
+
+	edges := make([]*auditEdgeResolver, len(r.logs))
+	for i, l := range r.logs {
+		edges[i] = &auditEdgeResolver{
+			cursor: r.cursors[i],
+			node: &auditResolver{
+				ctx:    r.ctx,
+				v:      l,
+				users:  r.users,
+				emotes: r.emotes,
+				fields: fields,
+			},
+		}
+	}
+
+	return edges
+}
+
+func (r *AuditLogConnectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{nextPage: r.nextPage, endCursor: r.endCursor}
+	if info.endCursor == nil && len(r.cursors) > 0 {
+		info.endCursor = &r.cursors[len(r.cursors)-1]
+	}
+
+	return info
+}
+
+func (r *auditEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+func (r *auditEdgeResolver) Node() *auditResolver {
+	return r.node
+}
+
 func (r *auditResolver) ID() string {
 	return r.v.ID.Hex()
 }
@@ -29,12 +379,17 @@ func (r *auditResolver) Type() int32 {
 	return r.v.Type
 }
 
-func (r *auditResolver) Target() auditTarget {
-	return auditTarget{}
+func (r *auditResolver) Target() *auditTargetResolver {
+	return &auditTargetResolver{r: r, v: r.v.Target}
 }
 
-func (r *auditResolver) Changes() []auditChange {
-	return []auditChange{}
+func (r *auditResolver) Changes() []*auditChangeResolver {
+	changes := make([]*auditChangeResolver, len(r.v.Changes))
+	for i, c := range r.v.Changes {
+		changes[i] = &auditChangeResolver{v: c}
+	}
+
+	return changes
 }
 
 func (r *auditResolver) Reason() *string {
@@ -45,12 +400,187 @@ func (r *auditResolver) CreatedBy() string {
 	return r.v.CreatedBy.Hex()
 }
 
-type auditChange struct {
-	Key    string `json:"key"`
-	Values []string
+func (r *auditResolver) ActionUser() (*UserResolver, error) {
+	if r.v.CreatedBy.IsZero() {
+		return nil, nil
+	}
+
+	return GenerateUserResolver(r.ctx, r.users[r.v.CreatedBy], &r.v.CreatedBy, childFields(r.fields, "action_user"))
+}
+
+// The metadata of the request the action was performed with, only visible to administrators
+func (r *auditResolver) Request() *datastructure.AuditLogRequest {
+	if usr, _ := r.ctx.Value(utils.UserKey).(*datastructure.User); !authorization.Can(r.ctx, usr, authorization.Administrate, nil) {
+		return nil
+	}
+
+	return r.v.Request
+}
+
+func (r *auditResolver) Reverts() *string {
+	if r.v.Reverts == nil {
+		return nil
+	}
+
+	id := r.v.Reverts.Hex()
+	return &id
+}
+
+// The emotes added to a channel by a channel emote entry
+func (r *auditResolver) AddedEmotes() (*[]*EmoteResolver, error) {
+	added, _, ok := auditEmoteDiff(r.v)
+	if !ok {
+		return nil, nil
+	}
+
+	return r.changedEmotes(added, childFields(r.fields, "added_emotes"))
+}
+
+// The emotes removed from a channel by a channel emote entry
+func (r *auditResolver) RemovedEmotes() (*[]*EmoteResolver, error) {
+	_, removed, ok := auditEmoteDiff(r.v)
+	if !ok {
+		return nil, nil
+	}
+
+	return r.changedEmotes(removed, childFields(r.fields, "removed_emotes"))
+}
+
+// Resolve the emotes of a channel emote entry, leaving out those the viewer may not see
+func (r *auditResolver) changedEmotes(ids []primitive.ObjectID, fields map[string]*SelectedField) (*[]*EmoteResolver, error) {
+	emotes := r.emotes
+	if emotes == nil {
+		found := []*datastructure.Emote{}
+		cur, err := mongo.Database.Collection("emotes").Find(r.ctx, bson.M{"_id": bson.M{"$in": ids}})
+		if err == nil {
+			err = cur.All(r.ctx, &found)
+		}
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+		emotes = make(map[primitive.ObjectID]*datastructure.Emote, len(found))
+		for _, e := range found {
+			emotes[e.ID] = e
+		}
+	}
+
+	viewer, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	result := []*EmoteResolver{}
+	for _, id := range ids {
+		e, ok := emotes[id]
+		if !ok || !e.IsVisibleTo(viewer) {
+			continue
+		}
+
+		resolver, err := GenerateEmoteResolver(r.ctx, e, nil, fields)
+		if err != nil {
+			return nil, err
+		}
+		if resolver != nil {
+			result = append(result, resolver)
+		}
+	}
+
+	return &result, nil
+}
+
+// Get the emotes added and removed by an entry changing the emotes of a channel
+func auditEmoteDiff(entry *datastructure.AuditLog) ([]primitive.ObjectID, []primitive.ObjectID, bool) {
+	if entry.Target == nil || entry.Target.Type != "users" {
+		return nil, nil, false
+	}
+
+	for _, c := range entry.Changes {
+		if c.Key != "emotes" {
+			continue
+		}
+		oldIDs, ok := audit.ObjectIDs(c.OldValue)
+		if !ok {
+			return nil, nil, false
+		}
+		newIDs, ok := audit.ObjectIDs(c.NewValue)
+		if !ok {
+			return nil, nil, false
+		}
+
+		added, removed := actions.DiffEmoteIDs(oldIDs, newIDs)
+		return added, removed, true
+	}
+
+	return nil, nil, false
+}
+
+func (r *auditResolver) CreatedAt() string {
+	return r.v.ID.Timestamp().Format(time.RFC3339)
+}
+
+type auditTargetResolver struct {
+	r *auditResolver
+	v *datastructure.Target
+}
+
+func (t *auditTargetResolver) ID() *string {
+	if t.v == nil || t.v.ID == nil {
+		return nil
+	}
+
+	id := t.v.ID.Hex()
+	return &id
+}
+
+func (t *auditTargetResolver) Type() string {
+	if t.v == nil {
+		return ""
+	}
+
+	return t.v.Type
 }
 
-type auditTarget struct {
-	ID   string `json:"id"`
-	Type string `json:"type"`
+func (t *auditTargetResolver) User() (*UserResolver, error) {
+	if t.v == nil || t.v.ID == nil || t.v.Type != "users" {
+		return nil, nil
+	}
+
+	return GenerateUserResolver(t.r.ctx, t.r.users[*t.v.ID], t.v.ID, childFields(t.r.fields, "target", "user"))
+}
+
+func (t *auditTargetResolver) Emote() (*EmoteResolver, error) {
+	if t.v == nil || t.v.ID == nil || t.v.Type != "emotes" {
+		return nil, nil
+	}
+
+	return GenerateEmoteResolver(t.r.ctx, t.r.emotes[*t.v.ID], t.v.ID, childFields(t.r.fields, "target", "emote"))
+}
+
+type auditChangeResolver struct {
+	v *datastructure.AuditLogChange
+}
+
+func (c *auditChangeResolver) Key() string {
+	return c.v.Key
+}
+
+// The old and new value, encoded as JSON
+func (c *auditChangeResolver) Values() []string {
+	return []string{encodeAuditValue(c.v.OldValue), encodeAuditValue(c.v.NewValue)}
+}
+
+func (c *auditChangeResolver) OldValue() string {
+	return encodeAuditValue(c.v.OldValue)
+}
+
+func (c *auditChangeResolver) NewValue() string {
+	return encodeAuditValue(c.v.NewValue)
+}
+
+// Encode a stored change value as JSON
+func encodeAuditValue(v interface{}) string {
+	b, err := json.Marshal(audit.PlainValue(v))
+	if err != nil {
+		log.Errorf("json, err=%v", err)
+		return "null"
+	}
+
+	return string(b)
 }
@@ -0,0 +1,145 @@
+package query_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type ChannelEmoteScheduleResolver struct {
+	ctx context.Context
+	v   *datastructure.ChannelEmoteSchedule
+
+	fields map[string]*SelectedField
+}
+
+func GenerateChannelEmoteScheduleResolver(ctx context.Context, schedule *datastructure.ChannelEmoteSchedule, fields map[string]*SelectedField) (*ChannelEmoteScheduleResolver, error) {
+	return &ChannelEmoteScheduleResolver{
+		ctx:    ctx,
+		v:      schedule,
+		fields: fields,
+	}, nil
+}
+
+func (*QueryResolver) ChannelEmoteSchedules(ctx context.Context, args struct {
+	ChannelID       string
+	IncludeFinished *bool
+}) ([]*ChannelEmoteScheduleResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	channelID, err := primitive.ObjectIDFromHex(args.ChannelID)
+	if err != nil {
+		return nil, resolvers.ErrUnknownChannel
+	}
+
+	channel, err := actions.GetChannel(ctx, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorization.Authorize(ctx, usr, authorization.ChannelEdit, channel); err != nil {
+		return nil, err
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	query := bson.M{"channel_id": channelID}
+	if args.IncludeFinished == nil || !*args.IncludeFinished {
+		query["status"] = bson.M{"$in": []int32{
+			datastructure.ChannelEmoteScheduleStatusPending,
+			datastructure.ChannelEmoteScheduleStatusRunning,
+		}}
+	}
+
+	schedules := []*datastructure.ChannelEmoteSchedule{}
+	cur, err := mongo.Database.Collection("channel_emote_schedules").Find(ctx, query, options.Find().SetSort(bson.M{"execute_at": 1}).SetLimit(resolvers.QueryLimit))
+	if err == nil {
+		err = cur.All(ctx, &schedules)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*ChannelEmoteScheduleResolver, len(schedules))
+	for i, s := range schedules {
+		r, err := GenerateChannelEmoteScheduleResolver(ctx, s, field.Children)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = r
+	}
+
+	return result, nil
+}
+
+func (r *ChannelEmoteScheduleResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *ChannelEmoteScheduleResolver) ChannelID() string {
+	return r.v.ChannelID.Hex()
+}
+
+func (r *ChannelEmoteScheduleResolver) Action() string {
+	return r.v.Action
+}
+
+func (r *ChannelEmoteScheduleResolver) EmoteIDs() []string {
+	ids := make([]string, len(r.v.EmoteIDs))
+	for i, id := range r.v.EmoteIDs {
+		ids[i] = id.Hex()
+	}
+	return ids
+}
+
+func (r *ChannelEmoteScheduleResolver) ExecuteAt() string {
+	return r.v.ExecuteAt.Format(time.RFC3339)
+}
+
+func (r *ChannelEmoteScheduleResolver) Status() int32 {
+	return r.v.Status
+}
+
+func (r *ChannelEmoteScheduleResolver) Reason() *string {
+	return r.v.Reason
+}
+
+func (r *ChannelEmoteScheduleResolver) CreatedByID() string {
+	return r.v.CreatedByID.Hex()
+}
+
+func (r *ChannelEmoteScheduleResolver) CreatedAt() string {
+	return r.v.CreatedAt.Format(time.RFC3339)
+}
+
+func (r *ChannelEmoteScheduleResolver) ExecutedAt() *string {
+	if r.v.ExecutedAt == nil {
+		return nil
+	}
+	s := r.v.ExecutedAt.Format(time.RFC3339)
+	return &s
+}
+
+func (r *ChannelEmoteScheduleResolver) Error() *string {
+	return r.v.Error
+}
+
+func (r *ChannelEmoteScheduleResolver) CreatedBy() (*UserResolver, error) {
+	return GenerateUserResolver(r.ctx, nil, &r.v.CreatedByID, r.fields["created_by"].Children)
+}
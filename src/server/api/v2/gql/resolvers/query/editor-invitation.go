@@ -0,0 +1,94 @@
+package query_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type EditorInvitationResolver struct {
+	ctx context.Context
+	v   *datastructure.EditorInvitation
+
+	fields map[string]*SelectedField
+}
+
+func GenerateEditorInvitationResolver(ctx context.Context, invitation *datastructure.EditorInvitation, fields map[string]*SelectedField) (*EditorInvitationResolver, error) {
+	return &EditorInvitationResolver{
+		ctx:    ctx,
+		v:      invitation,
+		fields: fields,
+	}, nil
+}
+
+// Get the pending invitations of this user to become an editor, newest first
+func (r *UserResolver) EditorInvitations() ([]*EditorInvitationResolver, error) {
+	usr, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if err := authorization.Authorize(r.ctx, usr, authorization.UserPrivateView, r.v); err != nil {
+		return nil, err
+	}
+
+	invitations := []*datastructure.EditorInvitation{}
+	cur, err := mongo.Database.Collection("editor_invitations").Find(r.ctx, bson.M{
+		"user_id": r.v.ID,
+		"status":  datastructure.EditorInvitationStatusPending,
+	}, options.Find().SetSort(bson.M{"_id": -1}).SetLimit(resolvers.QueryLimit))
+	if err == nil {
+		err = cur.All(r.ctx, &invitations)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*EditorInvitationResolver, len(invitations))
+	for i, inv := range invitations {
+		res, err := GenerateEditorInvitationResolver(r.ctx, inv, r.fields["editor_invitations"].Children)
+		if err != nil {
+			return nil, err
+		}
+		result[i] = res
+	}
+
+	return result, nil
+}
+
+func (r *EditorInvitationResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *EditorInvitationResolver) ChannelID() string {
+	return r.v.ChannelID.Hex()
+}
+
+func (r *EditorInvitationResolver) Channel() (*UserResolver, error) {
+	return GenerateUserResolver(r.ctx, nil, &r.v.ChannelID, r.fields["channel"].Children)
+}
+
+func (r *EditorInvitationResolver) Permissions() int32 {
+	return int32(r.v.Permissions)
+}
+
+func (r *EditorInvitationResolver) Reason() *string {
+	return r.v.Reason
+}
+
+func (r *EditorInvitationResolver) CreatedByID() string {
+	return r.v.CreatedByID.Hex()
+}
+
+func (r *EditorInvitationResolver) CreatedBy() (*UserResolver, error) {
+	return GenerateUserResolver(r.ctx, nil, &r.v.CreatedByID, r.fields["created_by"].Children)
+}
+
+func (r *EditorInvitationResolver) CreatedAt() string {
+	return r.v.CreatedAt.Format(time.RFC3339)
+}
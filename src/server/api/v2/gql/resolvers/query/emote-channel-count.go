@@ -0,0 +1,56 @@
+package query_resolvers
+
+import (
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type emoteChannelCountSampleResolver struct {
+	v *datastructure.EmoteChannelCountSample
+}
+
+func (r *EmoteResolver) ChannelCountHistory(args struct {
+	Days *int32
+}) ([]*emoteChannelCountSampleResolver, error) {
+	days := int32(30)
+	if args.Days != nil {
+		days = *args.Days
+	}
+	if days < 1 || days > 365 {
+		return nil, resolvers.ErrInvalidAmount
+	}
+
+	samples := []*datastructure.EmoteChannelCountSample{}
+	cur, err := mongo.Database.Collection("emote_channel_counts").Find(r.ctx, bson.M{
+		"emote_id":  r.v.ID,
+		"timestamp": bson.M{"$gte": time.Now().AddDate(0, 0, -int(days))},
+	}, options.Find().SetSort(bson.M{"timestamp": 1}))
+	if err == nil {
+		err = cur.All(r.ctx, &samples)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*emoteChannelCountSampleResolver, len(samples))
+	for i, s := range samples {
+		result[i] = &emoteChannelCountSampleResolver{v: s}
+	}
+
+	return result, nil
+}
+
+func (r *emoteChannelCountSampleResolver) Count() int32 {
+	return r.v.Count
+}
+
+func (r *emoteChannelCountSampleResolver) Timestamp() string {
+	return r.v.Timestamp.Format(time.RFC3339)
+}
@@ -0,0 +1,79 @@
+package query_resolvers
+
+import (
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func (r *UserResolver) FavoriteEmotes(args struct {
+	Page  *int32
+	Limit *int32
+}) ([]*EmoteResolver, error) {
+	limit := int64(20)
+	if args.Limit != nil {
+		limit = int64(*args.Limit)
+	}
+	if limit < 1 {
+		return nil, resolvers.ErrInvalidAmount
+	}
+	if limit > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+	page := int64(1)
+	if args.Page != nil && *args.Page > 1 {
+		page = int64(*args.Page)
+	}
+
+	// Hide the emotes the actor may not see before paginating, with the same rules as Emote.IsVisibleTo
+	visible := bson.M{"status": bson.M{"$ne": datastructure.EmoteStatusDeleted}}
+	usr, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if !authorization.Can(r.ctx, usr, authorization.EmoteModerate, nil) {
+		public := bson.M{
+			"status":     bson.M{"$ne": datastructure.EmoteStatusDisabled},
+			"visibility": bson.M{"$bitsAllClear": datastructure.EmoteVisibilityPrivate},
+		}
+		if usr != nil {
+			visible["$or"] = bson.A{public, bson.M{"owner": usr.ID}}
+		} else {
+			visible["$and"] = bson.A{public}
+		}
+	}
+
+	emotes := []*datastructure.Emote{}
+	cur, err := mongo.Database.Collection("emote_favorites").Aggregate(r.ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"user_id": r.v.ID}}},
+		{{Key: "$sort", Value: bson.M{"_id": -1}}},
+		{{Key: "$lookup", Value: bson.M{
+			"from":         "emotes",
+			"localField":   "emote_id",
+			"foreignField": "_id",
+			"as":           "emote",
+		}}},
+		{{Key: "$unwind", Value: "$emote"}},
+		{{Key: "$replaceRoot", Value: bson.M{"newRoot": "$emote"}}},
+		{{Key: "$match", Value: visible}},
+		{{Key: "$skip", Value: (page - 1) * limit}},
+		{{Key: "$limit", Value: limit}},
+	})
+	if err == nil {
+		err = cur.All(r.ctx, &emotes)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*EmoteResolver, len(emotes))
+	for i, e := range emotes {
+		if result[i], err = GenerateEmoteResolver(r.ctx, e, nil, r.fields["favorite_emotes"].Children); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
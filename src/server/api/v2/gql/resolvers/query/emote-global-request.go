@@ -0,0 +1,109 @@
+package query_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type emoteGlobalRequestResolver struct {
+	ctx context.Context
+	v   *datastructure.EmoteGlobalRequest
+}
+
+func (*QueryResolver) GlobalEmoteRequests(ctx context.Context, args struct {
+	Page  *int32
+	Limit *int32
+}) ([]*EmoteResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	limit := int64(20)
+	if args.Limit != nil {
+		limit = int64(*args.Limit)
+	}
+	if limit > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+	page := int64(1)
+	if args.Page != nil && *args.Page > 1 {
+		page = int64(*args.Page)
+	}
+
+	// Oldest requests come first
+	emotes := []*datastructure.Emote{}
+	cur, err := mongo.Database.Collection("emotes").Find(ctx, bson.M{
+		"status": datastructure.EmoteStatusPending,
+	}, options.Find().SetSort(bson.M{"global_request.requested_at": 1}).SetSkip((page-1)*limit).SetLimit(limit))
+	if err == nil {
+		err = cur.All(ctx, &emotes)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*EmoteResolver, len(emotes))
+	for i, e := range emotes {
+		if result[i], err = GenerateEmoteResolver(ctx, e, nil, field.Children); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (r *emoteGlobalRequestResolver) RequestedByID() string {
+	return r.v.RequestedByID.Hex()
+}
+
+func (r *emoteGlobalRequestResolver) RequestedAt() string {
+	return r.v.RequestedAt.Format(time.RFC3339)
+}
+
+func (r *emoteGlobalRequestResolver) Reason() *string {
+	return r.v.Reason
+}
+
+func (r *emoteGlobalRequestResolver) Approved() *bool {
+	return r.v.Approved
+}
+
+func (r *emoteGlobalRequestResolver) DecidedByID() *string {
+	if r.v.DecidedByID == nil {
+		return nil
+	}
+	hex := r.v.DecidedByID.Hex()
+	return &hex
+}
+
+func (r *emoteGlobalRequestResolver) DecidedAt() *string {
+	if r.v.DecidedAt == nil {
+		return nil
+	}
+	s := r.v.DecidedAt.Format(time.RFC3339)
+	return &s
+}
+
+func (r *emoteGlobalRequestResolver) DecisionReason() *string {
+	return r.v.DecisionReason
+}
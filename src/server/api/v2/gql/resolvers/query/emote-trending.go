@@ -0,0 +1,110 @@
+package query_resolvers
+
+import (
+	"context"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (*QueryResolver) TrendingEmotes(ctx context.Context, args struct {
+	Window *string
+	Page   *int32
+	Limit  *int32
+}) ([]*EmoteResolver, error) {
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	window, err := parseTrendingWindow(args.Window)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := int64(20)
+	if args.Limit != nil {
+		limit = int64(*args.Limit)
+	}
+	if limit > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+	page := int64(1)
+	if args.Page != nil && *args.Page > 1 {
+		page = int64(*args.Page)
+	}
+
+	key := "trending." + window
+	match := bson.M{
+		"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
+		key:      bson.M{"$gt": 0},
+	}
+
+	// Private and hidden emotes are only listed for their owner and moderators
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !authorization.Can(ctx, usr, authorization.EmoteModerate, nil) {
+		var usrID primitive.ObjectID
+		if usr != nil {
+			usrID = usr.ID
+		}
+
+		match["$or"] = bson.A{
+			bson.M{"visibility": bson.M{"$bitsAllClear": int32(datastructure.EmoteVisibilityPrivate | datastructure.EmoteVisibilityHidden)}},
+			bson.M{"owner": usrID},
+		}
+	}
+
+	emotes := []*datastructure.Emote{}
+	cur, err := mongo.Database.Collection("emotes").Find(ctx, match, options.Find().
+		SetSort(bson.D{{Key: key, Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip((page-1)*limit).
+		SetLimit(limit),
+	)
+	if err == nil {
+		err = cur.All(ctx, &emotes)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*EmoteResolver, len(emotes))
+	for i, e := range emotes {
+		if result[i], err = GenerateEmoteResolver(ctx, e, nil, field.Children); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (r *EmoteResolver) TrendingScore(args struct {
+	Window *string
+}) (int32, error) {
+	window, err := parseTrendingWindow(args.Window)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.v.Trending.Score(window), nil
+}
+
+// Get a trending window, defaulting to a week
+func parseTrendingWindow(window *string) (string, error) {
+	if window == nil {
+		return datastructure.EmoteTrendingWindowWeek, nil
+	}
+	if _, ok := actions.EmoteTrendingWindows[*window]; !ok {
+		return "", resolvers.ErrInvalidTrendingWindow
+	}
+
+	return *window, nil
+}
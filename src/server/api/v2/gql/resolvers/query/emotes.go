@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/cache"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
@@ -68,8 +69,8 @@ func GenerateEmoteResolver(ctx context.Context, emote *datastructure.Emote, emot
 		}
 	}
 
-	usr, usrValid := ctx.Value(utils.UserKey).(*datastructure.User)
-	if v, ok := fields["reports"]; ok && usrValid && (usr.Rank != datastructure.UserRankAdmin && usr.Rank != datastructure.UserRankModerator) && emote.Reports == nil {
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if v, ok := fields["reports"]; ok && authorization.Can(ctx, usr, authorization.ReportsView, nil) && emote.Reports == nil {
 		emote.Reports = &[]*datastructure.Report{}
 		if err := cache.Find(ctx, "reports", fmt.Sprintf("reports:%s", emote.ID.Hex()), bson.M{
 			"target.id":   emote.ID,
@@ -156,9 +157,29 @@ func (r *EmoteResolver) CreatedAt() string {
 }
 
 func (r *EmoteResolver) ChannelCount() int32 {
+	// The count is not known until the emote has been checked by the channel count worker
+	if r.v.ChannelCount == nil {
+		return 0
+	}
 	return *r.v.ChannelCount
 }
 
+func (r *EmoteResolver) FavoriteCount() int32 {
+	return r.v.FavoriteCount
+}
+
+func (r *EmoteResolver) SearchScore() *float64 {
+	return r.v.SearchScore
+}
+
+func (r *EmoteResolver) ChannelCountCheckedAt() *string {
+	if r.v.LastChannelCountCheck == nil {
+		return nil
+	}
+	s := r.v.LastChannelCountCheck.Format(time.RFC3339)
+	return &s
+}
+
 func (r *EmoteResolver) Owner() (*UserResolver, error) {
 	resolver, err := GenerateUserResolver(r.ctx, r.v.Owner, &r.v.OwnerID, r.fields["owner"].Children)
 	if err != nil {
@@ -207,9 +228,9 @@ func (r *EmoteResolver) Channels() (*[]*UserResolver, error) {
 }
 
 func (r *EmoteResolver) Reports() (*[]*reportResolver, error) {
-	u, ok := r.ctx.Value(utils.UserKey).(*datastructure.User)
-	if !ok || (u.Rank != datastructure.UserRankAdmin && u.Rank != datastructure.UserRankModerator) {
-		return nil, resolvers.ErrAccessDenied
+	u, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if err := authorization.Authorize(r.ctx, u, authorization.ReportsView, nil); err != nil {
+		return nil, err
 	}
 
 	if r.v.Reports == nil {
@@ -273,3 +294,31 @@ func (r *EmoteResolver) Height() []int32 {
 
 	return result
 }
+
+func (r *EmoteResolver) GlobalRequest() *emoteGlobalRequestResolver {
+	if r.v.GlobalRequest == nil {
+		return nil
+	}
+
+	// Only the owner, the requester and moderators may see the request
+	usr, ok := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok || (usr.ID != r.v.GlobalRequest.RequestedByID && !authorization.Can(r.ctx, usr, authorization.EmoteDetailsView, r.v)) {
+		return nil
+	}
+
+	return &emoteGlobalRequestResolver{ctx: r.ctx, v: r.v.GlobalRequest}
+}
+
+func (r *EmoteResolver) DisableReason() *string {
+	if r.v.DisableReason == nil {
+		return nil
+	}
+
+	// Only the owner and moderators may see why the emote was disabled
+	usr, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if !authorization.Can(r.ctx, usr, authorization.EmoteDetailsView, r.v) {
+		return nil
+	}
+
+	return r.v.DisableReason
+}
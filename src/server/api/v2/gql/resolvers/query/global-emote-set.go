@@ -0,0 +1,180 @@
+package query_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GlobalEmoteSetResolver struct {
+	ctx context.Context
+	v   *datastructure.GlobalEmoteSet
+
+	fields map[string]*SelectedField
+}
+
+func GenerateGlobalEmoteSetResolver(ctx context.Context, set *datastructure.GlobalEmoteSet, fields map[string]*SelectedField) (*GlobalEmoteSetResolver, error) {
+	return &GlobalEmoteSetResolver{
+		ctx:    ctx,
+		v:      set,
+		fields: fields,
+	}, nil
+}
+
+func (*QueryResolver) GlobalEmotes(ctx context.Context) ([]*EmoteResolver, error) {
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	query := bson.M{
+		"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
+	}
+
+	// Serve the union of the active sets, or the emotes flagged as global while no sets exist
+	count, err := mongo.Database.Collection("global_emote_sets").CountDocuments(ctx, bson.M{})
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+	if count > 0 {
+		ids, err := actions.GetActiveGlobalEmoteIDs(ctx, time.Now())
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+		query["_id"] = bson.M{"$in": ids}
+	} else {
+		query["visibility"] = bson.M{"$bitsAllSet": datastructure.EmoteVisibilityGlobal}
+	}
+
+	emotes := []*datastructure.Emote{}
+	cur, err := mongo.Database.Collection("emotes").Find(ctx, query)
+	if err == nil {
+		err = cur.All(ctx, &emotes)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*EmoteResolver, len(emotes))
+	for i, e := range emotes {
+		result[i], err = GenerateEmoteResolver(ctx, e, nil, field.Children)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (*QueryResolver) GlobalEmoteSets(ctx context.Context, args struct {
+	ActiveOnly *bool
+}) ([]*GlobalEmoteSetResolver, error) {
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	query := bson.M{}
+	if args.ActiveOnly != nil && *args.ActiveOnly {
+		query = actions.ActiveGlobalEmoteSetFilter(time.Now())
+	}
+
+	sets := []*datastructure.GlobalEmoteSet{}
+	cur, err := mongo.Database.Collection("global_emote_sets").Find(ctx, query, options.Find().SetSort(bson.M{"start_at": 1}))
+	if err == nil {
+		err = cur.All(ctx, &sets)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*GlobalEmoteSetResolver, len(sets))
+	for i, s := range sets {
+		result[i], _ = GenerateGlobalEmoteSetResolver(ctx, s, field.Children)
+	}
+
+	return result, nil
+}
+
+func (r *GlobalEmoteSetResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *GlobalEmoteSetResolver) Name() string {
+	return r.v.Name
+}
+
+func (r *GlobalEmoteSetResolver) EmoteIDs() []string {
+	ids := make([]string, len(r.v.EmoteIDs))
+	for i, id := range r.v.EmoteIDs {
+		ids[i] = id.Hex()
+	}
+	return ids
+}
+
+func (r *GlobalEmoteSetResolver) Emotes() ([]*EmoteResolver, error) {
+	emotes := []*datastructure.Emote{}
+	if len(r.v.EmoteIDs) > 0 {
+		cur, err := mongo.Database.Collection("emotes").Find(r.ctx, bson.M{
+			"_id":    bson.M{"$in": r.v.EmoteIDs},
+			"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
+		})
+		if err == nil {
+			err = cur.All(r.ctx, &emotes)
+		}
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+	}
+
+	result := make([]*EmoteResolver, len(emotes))
+	for i, e := range emotes {
+		var err error
+		result[i], err = GenerateEmoteResolver(r.ctx, e, nil, r.fields["emotes"].Children)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+func (r *GlobalEmoteSetResolver) StartAt() *string {
+	if r.v.StartAt == nil {
+		return nil
+	}
+	s := r.v.StartAt.Format(time.RFC3339)
+	return &s
+}
+
+func (r *GlobalEmoteSetResolver) EndAt() *string {
+	if r.v.EndAt == nil {
+		return nil
+	}
+	s := r.v.EndAt.Format(time.RFC3339)
+	return &s
+}
+
+func (r *GlobalEmoteSetResolver) Active() bool {
+	return r.v.IsActive(time.Now())
+}
+
+func (r *GlobalEmoteSetResolver) CreatedByID() string {
+	return r.v.CreatedByID.Hex()
+}
+
+func (r *GlobalEmoteSetResolver) CreatedAt() string {
+	return r.v.CreatedAt.Format(time.RFC3339)
+}
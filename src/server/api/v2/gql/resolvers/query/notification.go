@@ -0,0 +1,85 @@
+package query_resolvers
+
+import (
+	"context"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type notificationResolver struct {
+	ctx context.Context
+	v   *datastructure.Notification
+}
+
+func (r *UserResolver) Notifications(args struct {
+	UnreadOnly *bool
+}) (*[]*notificationResolver, error) {
+	usr, ok := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok || usr.ID != r.v.ID {
+		return nil, resolvers.ErrAccessDenied
+	}
+	if err := authorization.Authorize(r.ctx, usr, authorization.UserPrivateView, r.v); err != nil {
+		return nil, err
+	}
+
+	query := bson.M{"user_id": r.v.ID}
+	if args.UnreadOnly != nil && *args.UnreadOnly {
+		query["read"] = false
+	}
+
+	notifications := []*datastructure.Notification{}
+	cur, err := mongo.Database.Collection("notifications").Find(r.ctx, query, options.Find().SetSort(bson.M{"_id": -1}).SetLimit(resolvers.QueryLimit))
+	if err == nil {
+		err = cur.All(r.ctx, &notifications)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*notificationResolver, len(notifications))
+	for i, n := range notifications {
+		result[i] = &notificationResolver{ctx: r.ctx, v: n}
+	}
+
+	return &result, nil
+}
+
+func (r *notificationResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *notificationResolver) Message() string {
+	return r.v.Message
+}
+
+func (r *notificationResolver) TargetID() *string {
+	if r.v.Target == nil || r.v.Target.ID == nil {
+		return nil
+	}
+	hex := r.v.Target.ID.Hex()
+	return &hex
+}
+
+func (r *notificationResolver) TargetType() *string {
+	if r.v.Target == nil {
+		return nil
+	}
+	return &r.v.Target.Type
+}
+
+func (r *notificationResolver) Read() bool {
+	return r.v.Read
+}
+
+func (r *notificationResolver) CreatedAt() string {
+	return r.v.CreatedAt.Format(time.RFC3339)
+}
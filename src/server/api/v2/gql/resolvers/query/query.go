@@ -6,10 +6,14 @@ import (
 	"math"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/cache"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/search"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	api_proxy "github.com/SevenTV/ServerGo/src/server/api/v2/proxy"
 	"github.com/SevenTV/ServerGo/src/utils"
@@ -65,14 +69,6 @@ func GenerateSelectedFieldMap(ctx context.Context, max int) (*SelectedField, boo
 	}, depth > max
 }
 
-func (*QueryResolver) AuditLogs(ctx context.Context, args struct {
-	Page  int32
-	Limit int32
-}) ([]*auditResolver, error) {
-
-	return nil, nil
-}
-
 func (*QueryResolver) User(ctx context.Context, args struct{ ID string }) (*UserResolver, error) {
 	isMe := args.ID == "@me" // Handle @me (current authenticated user)
 	user := &datastructure.User{}
@@ -127,6 +123,35 @@ func (*QueryResolver) Role(ctx context.Context, args struct{ ID string }) (*Role
 	return GenerateRoleResolver(ctx, nil, &id, field.Children)
 }
 
+func (*QueryResolver) Roles(ctx context.Context) ([]*RoleResolver, error) {
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	roles := []*datastructure.Role{}
+	cur, err := mongo.Database.Collection("roles").Find(ctx, bson.M{}, options.Find().SetSort(bson.D{
+		{Key: "position", Value: -1},
+		{Key: "_id", Value: 1},
+	}))
+	if err == nil {
+		err = cur.All(ctx, &roles)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*RoleResolver, len(roles))
+	for i, role := range roles {
+		if result[i], err = GenerateRoleResolver(ctx, role, nil, field.Children); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
 func (*QueryResolver) Emote(ctx context.Context, args struct{ ID string }) (*EmoteResolver, error) {
 	id, err := primitive.ObjectIDFromHex(args.ID)
 	if err != nil {
@@ -145,10 +170,12 @@ func (*QueryResolver) Emote(ctx context.Context, args struct{ ID string }) (*Emo
 	// Get actor user
 	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
 	// Verify actor permissions
-	if utils.BitField.HasBits(int64(resolver.v.Visibility), int64(datastructure.EmoteVisibilityPrivate)) && usr.ID != resolver.v.OwnerID {
-		if usr == nil || !usr.HasPermission(datastructure.RolePermissionEmoteEditAll) {
-			return nil, resolvers.ErrUnknownEmote
-		}
+	if utils.BitField.HasBits(int64(resolver.v.Visibility), int64(datastructure.EmoteVisibilityPrivate)) && !authorization.Can(ctx, usr, authorization.EmoteDetailsView, resolver.v) {
+		return nil, resolvers.ErrUnknownEmote
+	}
+	// Disabled emotes are only resolved for their owner and moderators
+	if resolver.v.Status == datastructure.EmoteStatusDisabled && !authorization.Can(ctx, usr, authorization.EmoteDetailsView, resolver.v) {
+		return nil, resolvers.ErrUnknownEmote
 	}
 
 	return resolver, nil
@@ -167,6 +194,9 @@ func (*QueryResolver) Emotes(ctx context.Context, args struct{ List []string })
 			"_id": bson.M{
 				"$in": ids,
 			},
+			"status": bson.M{
+				"$ne": datastructure.EmoteStatusDisabled,
+			},
 		}, &emotes); err != nil {
 			log.Errorf("mongo, err=%v", err)
 			return nil, resolvers.ErrInternalServer
@@ -194,6 +224,7 @@ func (*QueryResolver) SearchEmotes(ctx context.Context, args struct {
 	Channel     *string
 	SubmittedBy *string
 	Filter      *EmoteSearchFilter
+	WithCount   bool
 }) ([]*EmoteResolver, error) {
 	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
 	if failed {
@@ -210,11 +241,6 @@ func (*QueryResolver) SearchEmotes(ctx context.Context, args struct {
 		return nil, resolvers.ErrQueryLimit
 	}
 
-	// Get the query parameter, used to search for specific emote names or tags
-	query := strings.Trim(args.Query, " ")
-	hasQuery := len(query) > 0
-	lQuery := fmt.Sprintf("(?i)%s", strings.ToLower(searchRegex.ReplaceAllString(query, "\\\\$0")))
-
 	// Pagination
 	page := int64(1)
 	if args.Page != nil && *args.Page > 1 {
@@ -225,14 +251,74 @@ func (*QueryResolver) SearchEmotes(ctx context.Context, args struct {
 		pageSize = int64(*args.PageSize)
 	}
 
+	q, err := buildEmoteSearch(ctx, emoteSearchArgs{
+		Query:       args.Query,
+		GlobalState: args.GlobalState,
+		SortBy:      args.SortBy,
+		SortOrder:   args.SortOrder,
+		Channel:     args.Channel,
+		SubmittedBy: args.SubmittedBy,
+		Filter:      args.Filter,
+	})
+	if err != nil {
+		return nil, err
+	}
+	q.Skip = (page - 1) * pageSize
+	q.Limit = int64(math.Max(0, math.Min(float64(pageSize), float64(limit))))
+
+	// Determine the full collection size, unless the client opted out as counting every match is expensive
+	if args.WithCount {
+		f := ctx.Value(utils.RequestCtxKey).(*fiber.Ctx) // Fiber context
+
+		// Count documents in the collection
+		count, err := search.Emotes.CountEmotes(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+
+		f.Response().Header.Add("X-Collection-Size", fmt.Sprint(count))
+	}
+
+	// Query the search backend
+	hits, err := search.Emotes.SearchEmotes(ctx, q)
+	if err != nil {
+		log.Errorf("search, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	// Resolve emotes
+	resolvers := make([]*EmoteResolver, len(hits))
+	for i, h := range hits {
+		resolvers[i], err = GenerateEmoteResolver(ctx, h.Emote, nil, field.Children)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return resolvers, nil
+}
+
+// The arguments shared by the emote searches
+type emoteSearchArgs struct {
+	Query       string
+	GlobalState *string
+	SortBy      *string
+	SortOrder   *int32
+	Channel     *string
+	SubmittedBy *string
+	Filter      *EmoteSearchFilter
+}
+
+// Build a search for emotes, only matching the emotes visible to the actor
+func buildEmoteSearch(ctx context.Context, args emoteSearchArgs) (search.EmoteQuery, error) {
+	// Get the query parameter, used to search for specific emote names or tags
+	query := strings.Trim(args.Query, " ")
+	hasQuery := len(query) > 0
+
 	// Get actor user
 	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
 
-	// Create aggregation
-	opts := options.Aggregate()
-	emotes := []*datastructure.Emote{}
 	match := bson.M{
-		"status": datastructure.EmoteStatusLive,
+		"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
 	}
 	if args.Channel != nil {
 		var targetChannel *datastructure.User
@@ -242,7 +328,7 @@ func (*QueryResolver) SearchEmotes(ctx context.Context, args struct {
 		}
 	}
 
-	if usr == nil || !usr.HasPermission(datastructure.RolePermissionEmoteEditAll) {
+	if !authorization.Can(ctx, usr, authorization.EmoteModerate, nil) {
 		var usrID primitive.ObjectID
 		if usr != nil {
 			usrID = usr.ID
@@ -257,21 +343,18 @@ func (*QueryResolver) SearchEmotes(ctx context.Context, args struct {
 	}
 
 	if args.Filter != nil {
-		if args.Filter.Visibility != nil {
-			match["visibility"] = bson.M{"$bitsAllSet": *args.Filter.Visibility}
+		if err := applyEmoteSearchFilter(ctx, match, args.Filter); err != nil {
+			return search.EmoteQuery{}, err
 		}
 	}
 
-	// Define aggregation pipeline
-	pipeline := mongo.Pipeline{}
-
 	// Get sorting direction
 	var order int32 = 1
 	if args.SortOrder != nil {
 		order = *args.SortOrder
 	}
 	if order > 1 {
-		return nil, resolvers.ErrInvalidSortOrder
+		return search.EmoteQuery{}, resolvers.ErrInvalidSortOrder
 	}
 	if order == 1 {
 		order = -1
@@ -280,46 +363,31 @@ func (*QueryResolver) SearchEmotes(ctx context.Context, args struct {
 	}
 
 	// Handle sorting
+	// Without a sort, results matching a query are ordered by relevance
+	sort := bson.D{}
 	if args.SortBy != nil {
 		sortBy := *args.SortBy
 		switch sortBy {
 		// Popularity Sort - Channels Added
 		case "popularity":
-			// Sort by channel count
-			pipeline = append(pipeline, []bson.D{
-				{primitive.E{Key: "$sort", Value: bson.D{
-					{Key: "channel_count", Value: -order},
-				}}},
-			}...)
+			sort = bson.D{{Key: "channel_count", Value: -order}}
+
+		// Trending Sort - Net channels added over the last week
+		case "trending":
+			sort = bson.D{
+				{Key: "trending." + datastructure.EmoteTrendingWindowWeek, Value: -order},
+				{Key: "channel_count", Value: -order},
+			}
 
 		// Creation Date Sort
 		case "age":
-			pipeline = append(pipeline, bson.D{primitive.E{Key: "$sort", Value: bson.D{
-				{Key: "_id", Value: order},
-			}}})
-		}
-	}
-
-	// Match & Limit
-	pipeline = append(pipeline, []bson.D{
-		{primitive.E{Key: "$match", Value: match}},                                                    // Match query
-		{primitive.E{Key: "$skip", Value: (page - 1) * pageSize}},                                     // Paginate
-		{primitive.E{Key: "$limit", Value: math.Max(0, math.Min(float64(pageSize), float64(limit)))}}, // Set limit
-	}...)
-
-	// If a query is specified, add sorting
-	if hasQuery {
-		match["$or"] = bson.A{
-			bson.M{
-				"name": bson.M{
-					"$regex": lQuery,
-				},
-			},
-			bson.M{
-				"tags": bson.M{
-					"$regex": lQuery,
-				},
-			},
+			sort = bson.D{{Key: "_id", Value: order}}
+
+		// Relevance Sort - Search Score
+		case "relevance":
+			if hasQuery {
+				sort = bson.D{{Key: "search_score", Value: -order}, {Key: "channel_count", Value: -1}}
+			}
 		}
 	}
 
@@ -334,44 +402,123 @@ func (*QueryResolver) SearchEmotes(ctx context.Context, args struct {
 		}
 	}
 
-	// Determine the full collection size
-	{
-		f := ctx.Value(utils.RequestCtxKey).(*fiber.Ctx) // Fiber context
+	return search.EmoteQuery{
+		Text:   query,
+		Filter: match,
+		Sort:   sort,
+	}, nil
+}
 
-		// Count documents in the collection
-		count, err := cache.GetCollectionSize(ctx, "emotes", match)
+type EmoteSearchFilter struct {
+	WidthRange        *[]*int16
+	Visibility        *int32
+	Animated          *bool
+	OwnerID           *string
+	Tag               *string
+	TagsAll           *[]string
+	TagsAny           *[]string
+	CreatedAfter      *string
+	CreatedBefore     *string
+	ChannelCountRange *[]*int32
+}
+
+// Add the conditions of a search filter to a query
+func applyEmoteSearchFilter(ctx context.Context, match bson.M, filter *EmoteSearchFilter) error {
+	conditions := bson.A{}
+
+	if filter.Visibility != nil {
+		match["visibility"] = bson.M{"$bitsAllSet": *filter.Visibility}
+	}
+	if filter.Animated != nil {
+		match["animated"] = *filter.Animated
+	}
+	if filter.OwnerID != nil {
+		id, err := primitive.ObjectIDFromHex(*filter.OwnerID)
 		if err != nil {
-			return nil, err
+			return resolvers.ErrUnknownUser
 		}
-
-		f.Response().Header.Add("X-Collection-Size", fmt.Sprint(count))
+		match["owner"] = id
 	}
 
-	// Query the DB
-	cur, err := mongo.Database.Collection("emotes").Aggregate(ctx, pipeline, opts)
+	// Width of the smallest size, the range is [min, max] where either bound may be omitted
+	if filter.WidthRange != nil {
+		r := *filter.WidthRange
+		if len(r) != 2 {
+			return resolvers.ErrInvalidFilter
+		}
+		cond := bson.M{}
+		if r[0] != nil {
+			cond["$gte"] = *r[0]
+		}
+		if r[1] != nil {
+			cond["$lte"] = *r[1]
+		}
+		if len(cond) > 0 {
+			conditions = append(conditions, bson.M{"width.0": cond})
+		}
+	}
+	if filter.ChannelCountRange != nil {
+		r := *filter.ChannelCountRange
+		if len(r) != 2 {
+			return resolvers.ErrInvalidFilter
+		}
+		cond := bson.M{}
+		if r[0] != nil {
+			cond["$gte"] = *r[0]
+		}
+		if r[1] != nil {
+			cond["$lte"] = *r[1]
+		}
+		if len(cond) > 0 {
+			conditions = append(conditions, bson.M{"channel_count": cond})
+		}
+	}
 
-	if err == nil {
-		err = cur.All(ctx, &emotes)
+	// Tags, normalized the same way as when they are written
+	for _, v := range []struct {
+		in *[]string
+		op string
+	}{{filter.TagsAll, "$all"}, {filter.TagsAny, "$in"}} {
+		if v.in == nil || len(*v.in) == 0 {
+			continue
+		}
+		tags, err := actions.NormalizeEmoteTags(ctx, *v.in)
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return resolvers.ErrInternalServer
+		}
+		conditions = append(conditions, bson.M{"tags": bson.M{v.op: tags}})
 	}
-	if err != nil {
-		log.Errorf("mongo, err=%v", err)
-		return nil, resolvers.ErrInternalServer
+	if filter.Tag != nil {
+		tags, err := actions.NormalizeEmoteTags(ctx, []string{*filter.Tag})
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return resolvers.ErrInternalServer
+		}
+		conditions = append(conditions, bson.M{"tags": tags[0]})
 	}
 
-	// Resolve emotes
-	resolvers := make([]*EmoteResolver, len(emotes))
-	for i, e := range emotes {
-		resolvers[i], err = GenerateEmoteResolver(ctx, e, nil, field.Children)
+	// Creation date, the ID of an emote holds its creation time
+	for _, v := range []struct {
+		in *string
+		op string
+	}{{filter.CreatedAfter, "$gte"}, {filter.CreatedBefore, "$lt"}} {
+		if v.in == nil {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, *v.in)
 		if err != nil {
-			return nil, err
+			return resolvers.ErrInvalidFilter
 		}
+		conditions = append(conditions, bson.M{"_id": bson.M{v.op: primitive.NewObjectIDFromTimestamp(t)}})
 	}
-	return resolvers, nil
-}
 
-type EmoteSearchFilter struct {
-	WidthRange *[]*int16
-	Visibility *int32
+	if len(conditions) > 0 {
+		and, _ := match["$and"].(bson.A)
+		match["$and"] = append(append(bson.A{}, and...), conditions...)
+	}
+
+	return nil
 }
 
 func (*QueryResolver) ThirdPartyEmotes(ctx context.Context, args struct {
@@ -433,9 +580,10 @@ func (*QueryResolver) ThirdPartyEmotes(ctx context.Context, args struct {
 }
 
 func (*QueryResolver) SearchUsers(ctx context.Context, args struct {
-	Query string
-	Page  *int32
-	Limit *int32
+	Query     string
+	Page      *int32
+	Limit     *int32
+	WithCount bool
 }) ([]*UserResolver, error) {
 	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
 	if failed {
@@ -479,8 +627,8 @@ func (*QueryResolver) SearchUsers(ctx context.Context, args struct {
 		return nil, resolvers.ErrInternalServer
 	}
 
-	// Determine the full collection size
-	{
+	// Determine the full collection size, unless the client opted out as counting every match is expensive
+	if args.WithCount {
 		f := ctx.Value(utils.RequestCtxKey).(*fiber.Ctx) // Fiber context
 
 		// Count documents in the collection
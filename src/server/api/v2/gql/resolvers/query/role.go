@@ -59,3 +59,7 @@ func (r *RoleResolver) Allowed() string {
 func (r *RoleResolver) Denied() string {
 	return fmt.Sprint(r.v.Denied)
 }
+
+func (r *RoleResolver) ChannelEmoteSlots() int32 {
+	return r.v.ChannelEmoteSlots
+}
@@ -0,0 +1,301 @@
+package query_resolvers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SevenTV/ServerGo/src/cache"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/search"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type EmoteConnectionResolver struct {
+	ctx      context.Context
+	q        search.EmoteQuery
+	hits     []*search.EmoteHit
+	nextPage bool
+
+	fields map[string]*SelectedField
+}
+
+type emoteEdgeResolver struct {
+	cursor string
+	node   *EmoteResolver
+}
+
+type UserConnectionResolver struct {
+	ctx      context.Context
+	match    bson.M
+	users    []*datastructure.User
+	cursors  []string
+	nextPage bool
+
+	fields map[string]*SelectedField
+}
+
+type userEdgeResolver struct {
+	cursor string
+	node   *UserResolver
+}
+
+type pageInfoResolver struct {
+	nextPage  bool
+	endCursor *string
+}
+
+func (*QueryResolver) SearchEmotesConnection(ctx context.Context, args struct {
+	Query       string
+	After       *string
+	Limit       *int32
+	GlobalState *string
+	SortBy      *string
+	SortOrder   *int32
+	Channel     *string
+	SubmittedBy *string
+	Filter      *EmoteSearchFilter
+}) (*EmoteConnectionResolver, error) {
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	limit, err := connectionLimit(args.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := buildEmoteSearch(ctx, emoteSearchArgs{
+		Query:       args.Query,
+		GlobalState: args.GlobalState,
+		SortBy:      args.SortBy,
+		SortOrder:   args.SortOrder,
+		Channel:     args.Channel,
+		SubmittedBy: args.SubmittedBy,
+		Filter:      args.Filter,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if args.After != nil {
+		q.After = *args.After
+	}
+
+	// Get one more result than requested to know whether there is a next page
+	q.Limit = limit + 1
+	hits, err := search.Emotes.SearchEmotes(ctx, q)
+	if err != nil {
+		if err == search.ErrInvalidCursor {
+			return nil, resolvers.ErrInvalidCursor
+		}
+		log.Errorf("search, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	nextPage := int64(len(hits)) > limit
+	if nextPage {
+		hits = hits[:limit]
+	}
+
+	return &EmoteConnectionResolver{
+		ctx:      ctx,
+		q:        q,
+		hits:     hits,
+		nextPage: nextPage,
+		fields:   field.Children,
+	}, nil
+}
+
+func (*QueryResolver) SearchUsersConnection(ctx context.Context, args struct {
+	Query string
+	After *string
+	Limit *int32
+}) (*UserConnectionResolver, error) {
+	field, failed := GenerateSelectedFieldMap(ctx, resolvers.MaxDepth)
+	if failed {
+		return nil, resolvers.ErrDepth
+	}
+
+	limit, err := connectionLimit(args.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	query := strings.Trim(args.Query, " ")
+	lQuery := fmt.Sprintf("(?i)%s", strings.ToLower(searchRegex.ReplaceAllString(query, "\\\\$0")))
+	match := bson.M{
+		"login": bson.M{
+			"$regex": lQuery,
+		},
+	}
+
+	// Logins are unique, so they alone give every user a distinct position
+	sort := bson.D{{Key: "login", Value: 1}}
+	filter := match
+	if args.After != nil {
+		values, err := search.DecodeCursor(sort, *args.After)
+		if err != nil {
+			return nil, resolvers.ErrInvalidCursor
+		}
+		filter = bson.M{"$and": bson.A{match, search.KeysetFilter(sort, values)}}
+	}
+
+	users := []*datastructure.User{}
+	cur, err := mongo.Database.Collection("users").Find(ctx, filter, options.Find().SetSort(sort).SetLimit(limit+1))
+	if err == nil {
+		err = cur.All(ctx, &users)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	nextPage := int64(len(users)) > limit
+	if nextPage {
+		users = users[:limit]
+	}
+
+	cursors := make([]string, len(users))
+	for i, u := range users {
+		if cursors[i], err = search.EncodeCursor(sort, bson.A{u.Login}); err != nil {
+			log.Errorf("search, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+	}
+
+	return &UserConnectionResolver{
+		ctx:      ctx,
+		match:    match,
+		users:    users,
+		cursors:  cursors,
+		nextPage: nextPage,
+		fields:   field.Children,
+	}, nil
+}
+
+func (r *EmoteConnectionResolver) Edges() ([]*emoteEdgeResolver, error) {
+	fields := childFields(r.fields, "edges", "node")
+
+	edges := make([]*emoteEdgeResolver, len(r.hits))
+	for i, h := range r.hits {
+		node, err := GenerateEmoteResolver(r.ctx, h.Emote, nil, fields)
+		if err != nil {
+			return nil, err
+		}
+		edges[i] = &emoteEdgeResolver{cursor: h.Cursor, node: node}
+	}
+
+	return edges, nil
+}
+
+func (r *EmoteConnectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{nextPage: r.nextPage}
+	if len(r.hits) > 0 {
+		info.endCursor = &r.hits[len(r.hits)-1].Cursor
+	}
+
+	return info
+}
+
+// The total amount of matching emotes is only counted when it is requested
+func (r *EmoteConnectionResolver) TotalCount() (int32, error) {
+	count, err := search.Emotes.CountEmotes(r.ctx, r.q)
+	if err != nil {
+		log.Errorf("search, err=%v", err)
+		return 0, resolvers.ErrInternalServer
+	}
+
+	return int32(count), nil
+}
+
+func (r *emoteEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+func (r *emoteEdgeResolver) Node() *EmoteResolver {
+	return r.node
+}
+
+func (r *UserConnectionResolver) Edges() ([]*userEdgeResolver, error) {
+	fields := childFields(r.fields, "edges", "node")
+
+	edges := make([]*userEdgeResolver, len(r.users))
+	for i, u := range r.users {
+		node, err := GenerateUserResolver(r.ctx, u, nil, fields)
+		if err != nil {
+			return nil, err
+		}
+		edges[i] = &userEdgeResolver{cursor: r.cursors[i], node: node}
+	}
+
+	return edges, nil
+}
+
+func (r *UserConnectionResolver) PageInfo() *pageInfoResolver {
+	info := &pageInfoResolver{nextPage: r.nextPage}
+	if len(r.cursors) > 0 {
+		info.endCursor = &r.cursors[len(r.cursors)-1]
+	}
+
+	return info
+}
+
+// The total amount of matching users is only counted when it is requested
+func (r *UserConnectionResolver) TotalCount() (int32, error) {
+	count, err := cache.GetCollectionSize(r.ctx, "users", r.match)
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return 0, resolvers.ErrInternalServer
+	}
+
+	return int32(count), nil
+}
+
+func (r *userEdgeResolver) Cursor() string {
+	return r.cursor
+}
+
+func (r *userEdgeResolver) Node() *UserResolver {
+	return r.node
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.nextPage
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	return r.endCursor
+}
+
+// Get the page size of a connection, defaulting to 20
+func connectionLimit(limit *int32) (int64, error) {
+	if limit == nil {
+		return 20, nil
+	}
+	if *limit < 1 {
+		return 0, resolvers.ErrInvalidAmount
+	}
+	if int64(*limit) > resolvers.QueryLimit {
+		return 0, resolvers.ErrQueryLimit
+	}
+
+	return int64(*limit), nil
+}
+
+// Get the selected children of a nested field, or none if it was not selected
+func childFields(fields map[string]*SelectedField, path ...string) map[string]*SelectedField {
+	for _, name := range path {
+		f, ok := fields[name]
+		if !ok {
+			return map[string]*SelectedField{}
+		}
+		fields = f.Children
+	}
+
+	return fields
+}
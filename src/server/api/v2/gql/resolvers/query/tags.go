@@ -0,0 +1,133 @@
+package query_resolvers
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type tagCountResolver struct {
+	v *datastructure.TagCount
+}
+
+type tagSynonymResolver struct {
+	v *datastructure.TagSynonym
+}
+
+func (*QueryResolver) TagSuggestions(ctx context.Context, args struct {
+	Prefix string
+	Limit  *int32
+}) ([]*tagCountResolver, error) {
+	prefix := strings.ToLower(strings.TrimSpace(args.Prefix))
+	if prefix == "" {
+		return []*tagCountResolver{}, nil
+	}
+
+	// The anchored, case sensitive pattern is served by the index on the tag,
+	// but the matching tags are then sorted by count in memory
+	return findTagCounts(ctx, bson.M{
+		"_id": bson.M{"$regex": "^" + regexp.QuoteMeta(prefix)},
+	}, args.Limit)
+}
+
+func (*QueryResolver) PopularTags(ctx context.Context, args struct {
+	Limit *int32
+}) ([]*tagCountResolver, error) {
+	return findTagCounts(ctx, bson.M{}, args.Limit)
+}
+
+func (*QueryResolver) TagSynonyms(ctx context.Context) ([]*tagSynonymResolver, error) {
+	usr, ok := ctx.Value(utils.UserKey).(*datastructure.User)
+	if !ok {
+		return nil, resolvers.ErrLoginRequired
+	}
+
+	if err := authorization.Authorize(ctx, usr, authorization.EmoteModerate, nil); err != nil {
+		return nil, err
+	}
+
+	synonyms := []*datastructure.TagSynonym{}
+	cur, err := mongo.Database.Collection("tag_synonyms").Find(ctx, bson.M{}, options.Find().SetSort(bson.M{"_id": 1}))
+	if err == nil {
+		err = cur.All(ctx, &synonyms)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*tagSynonymResolver, len(synonyms))
+	for i, s := range synonyms {
+		result[i] = &tagSynonymResolver{v: s}
+	}
+
+	return result, nil
+}
+
+// Get the most used tags matching a query
+func findTagCounts(ctx context.Context, query bson.M, limit *int32) ([]*tagCountResolver, error) {
+	l := int64(10)
+	if limit != nil {
+		l = int64(*limit)
+	}
+	if l < 1 {
+		return nil, resolvers.ErrInvalidAmount
+	}
+	if l > resolvers.QueryLimit {
+		return nil, resolvers.ErrQueryLimit
+	}
+
+	tags := []*datastructure.TagCount{}
+	cur, err := mongo.Database.Collection("emote_tags").Find(ctx, query, options.Find().
+		SetSort(bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}).
+		SetLimit(l),
+	)
+	if err == nil {
+		err = cur.All(ctx, &tags)
+	}
+	if err != nil {
+		log.Errorf("mongo, err=%v", err)
+		return nil, resolvers.ErrInternalServer
+	}
+
+	result := make([]*tagCountResolver, len(tags))
+	for i, t := range tags {
+		result[i] = &tagCountResolver{v: t}
+	}
+
+	return result, nil
+}
+
+func (r *tagCountResolver) Tag() string {
+	return r.v.Tag
+}
+
+func (r *tagCountResolver) Count() int32 {
+	return r.v.Count
+}
+
+func (r *tagSynonymResolver) Synonym() string {
+	return r.v.Synonym
+}
+
+func (r *tagSynonymResolver) Tag() string {
+	return r.v.Tag
+}
+
+func (r *tagSynonymResolver) CreatedByID() string {
+	return r.v.CreatedByID.Hex()
+}
+
+func (r *tagSynonymResolver) CreatedAt() string {
+	return r.v.CreatedAt.Format(time.RFC3339)
+}
@@ -0,0 +1,109 @@
+package query_resolvers
+
+import (
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
+	"github.com/SevenTV/ServerGo/src/utils"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// The audit log types shown in the activity feed of a channel
+var channelActivityTypes = []int32{
+	datastructure.AuditLogTypeUserChannelEmoteAdd,
+	datastructure.AuditLogTypeUserChannelEmoteRemove,
+	datastructure.AuditLogTypeUserChannelEmoteImport,
+	datastructure.AuditLogTypeUserChannelEmoteSet,
+	datastructure.AuditLogTypeUserChannelEmoteScheduleCreate,
+	datastructure.AuditLogTypeUserChannelEmoteScheduleCancel,
+	datastructure.AuditLogTypeUserChannelEditorAdd,
+	datastructure.AuditLogTypeUserChannelEditorRemove,
+	datastructure.AuditLogTypeUserChannelEditorPermissionsSet,
+	datastructure.AuditLogTypeUserChannelEditorInvite,
+	datastructure.AuditLogTypeUserChannelEditorInviteAccept,
+	datastructure.AuditLogTypeUserChannelEditorInviteDecline,
+	datastructure.AuditLogTypeUserChannelEditorInviteCancel,
+	datastructure.AuditLogTypeUserChannelEditorLeave,
+	datastructure.AuditLogTypeAuditRevert,
+}
+
+// The audit log types of the channel owner's emotes shown in the activity feed
+var channelEmoteActivityTypes = []int32{
+	datastructure.AuditLogTypeEmoteEdit,
+	datastructure.AuditLogTypeAuditRevert,
+}
+
+// Get what happened to the emotes and editors of a channel, newest first
+// Visible to the channel owner, their editors and moderators
+func (r *UserResolver) Activity(args struct {
+	After *string
+	Limit *int32
+	Types *[]int32
+}) (*AuditLogConnectionResolver, error) {
+	usr, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if err := authorization.Authorize(r.ctx, usr, authorization.ChannelActivityView, r.v); err != nil {
+		return nil, err
+	}
+
+	limit, err := connectionLimit(args.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var types []int32
+	if args.Types != nil {
+		types = *args.Types
+	}
+
+	or := bson.A{}
+	if t := filterActivityTypes(channelActivityTypes, types); len(t) > 0 {
+		or = append(or, bson.M{
+			"target.type": "users",
+			"target.id":   r.v.ID,
+			"type":        bson.M{"$in": t},
+		})
+	}
+	if t := filterActivityTypes(channelEmoteActivityTypes, types); len(t) > 0 {
+		emoteIDs, err := mongo.Database.Collection("emotes").Distinct(r.ctx, "_id", bson.M{"owner": r.v.ID})
+		if err != nil {
+			log.Errorf("mongo, err=%v", err)
+			return nil, resolvers.ErrInternalServer
+		}
+		if len(emoteIDs) > 0 {
+			or = append(or, bson.M{
+				"target.type": "emotes",
+				"target.id":   bson.M{"$in": emoteIDs},
+				"type":        bson.M{"$in": t},
+			})
+		}
+	}
+
+	match := bson.M{"$or": or}
+	if len(or) == 0 {
+		// None of the requested types are part of the activity feed
+		match = bson.M{"_id": bson.M{"$exists": false}}
+	}
+
+	return findAuditLogs(r.ctx, match, args.After, limit, childFields(r.fields, "activity"))
+}
+
+// Restrict the types of an activity feed to the requested ones, if any were requested
+func filterActivityTypes(allowed []int32, requested []int32) []int32 {
+	if len(requested) == 0 {
+		return allowed
+	}
+
+	types := []int32{}
+	for _, a := range allowed {
+		for _, t := range requested {
+			if a == t {
+				types = append(types, a)
+				break
+			}
+		}
+	}
+
+	return types
+}
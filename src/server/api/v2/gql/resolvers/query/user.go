@@ -5,10 +5,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/SevenTV/ServerGo/src/authorization"
 	"github.com/SevenTV/ServerGo/src/cache"
 	"github.com/SevenTV/ServerGo/src/mongo"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
-	"github.com/SevenTV/ServerGo/src/redis"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql/resolvers"
 	api_proxy "github.com/SevenTV/ServerGo/src/server/api/v2/proxy"
 	"github.com/SevenTV/ServerGo/src/utils"
@@ -23,6 +23,9 @@ type UserResolver struct {
 	ctx context.Context
 	v   *datastructure.User
 
+	// Permissions as an editor, set when listed in the editors of a channel or in the channels a user edits
+	editorPermissions *int64
+
 	fields map[string]*SelectedField
 }
 
@@ -50,7 +53,7 @@ func GenerateUserResolver(ctx context.Context, user *datastructure.User, userID
 		user.OwnedEmotes = &[]*datastructure.Emote{}
 		if err := cache.Find(ctx, "emotes", fmt.Sprintf("owner:%s", user.ID.Hex()), bson.M{
 			"owner":  user.ID,
-			"status": datastructure.EmoteStatusLive,
+			"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
 		}, user.OwnedEmotes); err != nil {
 			log.Errorf("mongo, err=%v", err)
 			return nil, resolvers.ErrInternalServer
@@ -96,6 +99,7 @@ func GenerateUserResolver(ctx context.Context, user *datastructure.User, userID
 				"_id": bson.M{
 					"$in": user.EmoteIDs,
 				},
+				"status": bson.M{"$in": datastructure.EmoteStatusesUsable},
 			}, user.Emotes); err != nil {
 				log.Errorf("mongo, err=%v", err)
 				return nil, resolvers.ErrInternalServer
@@ -159,13 +163,13 @@ func GenerateUserResolver(ctx context.Context, user *datastructure.User, userID
 		}
 	}
 
-	usr, usrValid := ctx.Value(utils.UserKey).(*datastructure.User)
-	if v, ok := fields["reports"]; ok && usrValid && (usr.Rank != datastructure.UserRankAdmin && usr.Rank != datastructure.UserRankModerator) && user.Reports == nil {
+	usr, _ := ctx.Value(utils.UserKey).(*datastructure.User)
+	if v, ok := fields["reports"]; ok && authorization.Can(ctx, usr, authorization.ReportsView, nil) && user.Reports == nil {
 		user.Reports = &[]*datastructure.Report{}
-		if err := cache.Find(ctx, "users", fmt.Sprintf("user:%s:reports", user.ID.Hex()), bson.M{
+		if err := cache.Find(ctx, "reports", fmt.Sprintf("user:%s:reports", user.ID.Hex()), bson.M{
 			"target.id":   user.ID,
 			"target.type": "users",
-		}, user.EditorIn); err != nil {
+		}, user.Reports); err != nil {
 			log.Errorf("mongo, err=%v", err)
 			return nil, resolvers.ErrInternalServer
 		}
@@ -203,7 +207,7 @@ func GenerateUserResolver(ctx context.Context, user *datastructure.User, userID
 		}
 	}
 
-	if _, ok := fields["bans"]; ok && usrValid && usr.HasPermission(datastructure.RolePermissionBanUsers) && user.Bans == nil {
+	if _, ok := fields["bans"]; ok && authorization.Can(ctx, usr, authorization.BansView, nil) && user.Bans == nil {
 		user.Bans = &[]*datastructure.Ban{}
 		res, err := mongo.Database.Collection("bans").Find(ctx, bson.M{
 			"user_id": user.ID,
@@ -233,7 +237,7 @@ func (r *UserResolver) ID() string {
 }
 
 func (r *UserResolver) Email() *string {
-	if u, ok := r.ctx.Value(utils.UserKey).(*datastructure.User); ok && (r.v.ID == u.ID || u.HasPermission(datastructure.RolePermissionManageUsers)) {
+	if u, _ := r.ctx.Value(utils.UserKey).(*datastructure.User); authorization.Can(r.ctx, u, authorization.UserPrivateView, r.v) {
 		return &r.v.Email
 	} else { // Hide the email address if
 		s := "<hidden>"
@@ -290,29 +294,44 @@ func (r *UserResolver) Editors() ([]*UserResolver, error) {
 	editors := *r.v.Editors
 	result := []*UserResolver{}
 	for _, e := range editors {
-		r, err := GenerateUserResolver(r.ctx, e, nil, r.fields["editors"].Children)
+		er, err := GenerateUserResolver(r.ctx, e, nil, r.fields["editors"].Children)
 		if err != nil {
 			log.Errorf("generation, err=%v", err)
 			return nil, resolvers.ErrInternalServer
 		}
-		if r != nil {
-			result = append(result, r)
+		if er != nil {
+			if permissions, ok := r.v.GetEditorPermissions(e.ID); ok {
+				er.editorPermissions = &permissions
+			}
+			result = append(result, er)
 		}
 	}
 	return result, nil
 }
 
+func (r *UserResolver) EditorPermissions() *int32 {
+	if r.editorPermissions == nil {
+		return nil
+	}
+
+	permissions := int32(*r.editorPermissions)
+	return &permissions
+}
+
 func (r *UserResolver) EditorIn() ([]*UserResolver, error) {
 	editors := *r.v.EditorIn
 	result := []*UserResolver{}
 	for _, e := range editors {
-		r, err := GenerateUserResolver(r.ctx, e, nil, r.fields["editor_in"].Children)
+		er, err := GenerateUserResolver(r.ctx, e, nil, r.fields["editor_in"].Children)
 		if err != nil {
 			log.Errorf("generation, err=%v", err)
 			return nil, resolvers.ErrInternalServer
 		}
-		if r != nil {
-			result = append(result, r)
+		if er != nil {
+			if permissions, ok := e.GetEditorPermissions(r.v.ID); ok {
+				er.editorPermissions = &permissions
+			}
+			result = append(result, er)
 		}
 	}
 	return result, nil
@@ -396,9 +415,9 @@ func (r *UserResolver) ProfileImageURL() string {
 }
 
 func (r *UserResolver) Reports() (*[]*reportResolver, error) {
-	u, ok := r.ctx.Value(utils.UserKey).(*datastructure.User)
-	if !ok || (u.Rank != datastructure.UserRankAdmin && u.Rank != datastructure.UserRankModerator) {
-		return nil, resolvers.ErrAccessDenied
+	u, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if err := authorization.Authorize(r.ctx, u, authorization.ReportsView, nil); err != nil {
+		return nil, err
 	}
 
 	if r.v.Reports == nil {
@@ -418,9 +437,9 @@ func (r *UserResolver) Reports() (*[]*reportResolver, error) {
 }
 
 func (r *UserResolver) Bans() (*[]*banResolver, error) {
-	u, ok := r.ctx.Value(utils.UserKey).(*datastructure.User)
-	if !ok || !u.HasPermission(datastructure.RolePermissionBanUsers) {
-		return nil, resolvers.ErrAccessDenied
+	u, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if err := authorization.Authorize(r.ctx, u, authorization.BansView, nil); err != nil {
+		return nil, err
 	}
 
 	if r.v.Bans == nil {
@@ -440,13 +459,14 @@ func (r *UserResolver) Bans() (*[]*banResolver, error) {
 }
 
 func (r *UserResolver) Banned() bool {
-	return redis.Client.HGet(r.ctx, "user:bans", r.v.ID.Hex()).Val() != ""
+	banned, _ := authorization.IsBanned(r.ctx, r.v.ID)
+	return banned
 }
 
 func (r *UserResolver) AuditEntries() (*[]string, error) {
-	u, ok := r.ctx.Value(utils.UserKey).(*datastructure.User)
-	if !ok || (u.Rank != datastructure.UserRankAdmin && u.Rank != datastructure.UserRankModerator) {
-		return nil, resolvers.ErrAccessDenied
+	u, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if err := authorization.Authorize(r.ctx, u, authorization.AuditLogsView, nil); err != nil {
+		return nil, err
 	}
 
 	if r.v.AuditEntries == nil {
@@ -463,3 +483,57 @@ func (r *UserResolver) AuditEntries() (*[]string, error) {
 	}
 	return &logs, nil
 }
+
+func (r *UserResolver) EmoteSlots() int32 {
+	return r.v.GetEmoteSlots()
+}
+
+func (r *UserResolver) EmoteSlotsUsed() int32 {
+	return int32(len(r.v.EmoteIDs))
+}
+
+func (r *UserResolver) EmoteSlotGrants() (*[]*emoteSlotGrantResolver, error) {
+	u, _ := r.ctx.Value(utils.UserKey).(*datastructure.User)
+	if err := authorization.Authorize(r.ctx, u, authorization.UserPrivateView, r.v); err != nil {
+		return nil, err
+	}
+
+	grants := make([]*emoteSlotGrantResolver, len(r.v.EmoteSlotGrants))
+	for i, g := range r.v.EmoteSlotGrants {
+		grants[i] = &emoteSlotGrantResolver{ctx: r.ctx, v: g}
+	}
+	return &grants, nil
+}
+
+type emoteSlotGrantResolver struct {
+	ctx context.Context
+	v   *datastructure.EmoteSlotGrant
+}
+
+func (r *emoteSlotGrantResolver) ID() string {
+	return r.v.ID.Hex()
+}
+
+func (r *emoteSlotGrantResolver) Amount() int32 {
+	return r.v.Amount
+}
+
+func (r *emoteSlotGrantResolver) ExpireAt() *string {
+	if r.v.ExpireAt == nil {
+		return nil
+	}
+	s := r.v.ExpireAt.Format(time.RFC3339)
+	return &s
+}
+
+func (r *emoteSlotGrantResolver) Expired() bool {
+	return r.v.ExpireAt != nil && r.v.ExpireAt.Before(time.Now())
+}
+
+func (r *emoteSlotGrantResolver) Reason() string {
+	return r.v.Reason
+}
+
+func (r *emoteSlotGrantResolver) GrantedByID() string {
+	return r.v.GrantedBy.Hex()
+}
@@ -1,6 +1,13 @@
 package resolvers
 
+import "time"
+
 const (
 	MaxDepth   = 4
 	QueryLimit = 150
+
+	// The amount of personal access tokens a user may have
+	MaxAccessTokens = 25
+	// How long personal access tokens may be valid for
+	MaxAccessTokenLifetime = time.Hour * 24 * 365
 )
@@ -0,0 +1,289 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SevenTV/ServerGo/src/authorization"
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
+	"github.com/SevenTV/ServerGo/src/server/middleware"
+	"github.com/SevenTV/ServerGo/src/utils"
+	"github.com/gofiber/fiber/v2"
+	log "github.com/sirupsen/logrus"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The current version of the channel emote export format
+// Bump this whenever the shape of ChannelEmoteExport changes
+const ChannelEmoteExportVersion int32 = 1
+
+type ChannelEmoteExport struct {
+	Version    int32                     `json:"version"`     // The version of the export format
+	ExportedAt time.Time                 `json:"exported_at"` // The time at which the export was created
+	Channel    ChannelEmoteExportChannel `json:"channel"`     // The channel the emotes were exported from
+	Emotes     []ChannelEmoteExportEntry `json:"emotes"`      // The channel's emote entries, in channel order
+}
+
+type ChannelEmoteExportChannel struct {
+	ID    string `json:"id"`
+	Login string `json:"login"`
+}
+
+type ChannelEmoteExportEntry struct {
+	ID    string  `json:"id"`    // The ID of the emote
+	Name  string  `json:"name"`  // The name of the emote at the time of export
+	Alias *string `json:"alias"` // Reserved for per-channel aliases, which are not stored yet. Always null
+	Flags int32   `json:"flags"` // The emote's visibility flags at the time of export
+}
+
+type channelEmoteImportResult struct {
+	Status     int32                          `json:"status"`
+	Message    string                         `json:"message"`
+	DryRun     bool                           `json:"dry_run"`
+	Valid      bool                           `json:"valid"`
+	Applied    bool                           `json:"applied"`
+	EmoteCount int                            `json:"emote_count"` // The amount of emotes the channel has (or would have) after the import
+	Entries    []*channelEmoteImportEntryInfo `json:"entries"`
+}
+
+type channelEmoteImportEntryInfo struct {
+	ID     string  `json:"id"`
+	Name   string  `json:"name"`
+	Action string  `json:"action"` // One of "add", "remove", "unchanged" or "invalid"
+	Error  *string `json:"error"`
+}
+
+func ChannelEmotesRoute(router fiber.Router) {
+	//
+	// Export the emotes of a channel
+	//
+	router.Get("/:user/emotes/export", func(c *fiber.Ctx) error {
+		c.Set("Content-Type", "application/json")
+
+		channel, err := findUser(c.Context(), c.Params("user"))
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return c.Status(404).Send(utils.S2B(fmt.Sprintf(errNotFound, "Unknown User")))
+			}
+			log.Errorf("mongo, err=%v", err)
+			return c.Status(500).Send(errInternalServer)
+		}
+
+		emotes := []*datastructure.Emote{}
+		if len(channel.EmoteIDs) > 0 {
+			cur, err := mongo.Database.Collection("emotes").Find(c.Context(), bson.M{
+				"_id": bson.M{
+					"$in": channel.EmoteIDs,
+				},
+			})
+			if err == nil {
+				err = cur.All(c.Context(), &emotes)
+			}
+			if err != nil {
+				log.Errorf("mongo, err=%v", err)
+				return c.Status(500).Send(errInternalServer)
+			}
+		}
+
+		emoteMap := make(map[primitive.ObjectID]*datastructure.Emote, len(emotes))
+		for _, e := range emotes {
+			emoteMap[e.ID] = e
+		}
+
+		// Keep the order in which the emotes were added to the channel
+		entries := []ChannelEmoteExportEntry{}
+		for _, id := range channel.EmoteIDs {
+			e, ok := emoteMap[id]
+			if !ok {
+				continue
+			}
+
+			entries = append(entries, ChannelEmoteExportEntry{
+				ID:    e.ID.Hex(),
+				Name:  e.Name,
+				Flags: e.Visibility,
+			})
+		}
+
+		b, err := json.Marshal(ChannelEmoteExport{
+			Version:    ChannelEmoteExportVersion,
+			ExportedAt: time.Now(),
+			Channel: ChannelEmoteExportChannel{
+				ID:    channel.ID.Hex(),
+				Login: channel.Login,
+			},
+			Emotes: entries,
+		})
+		if err != nil {
+			log.Errorf("json, err=%v", err)
+			return c.Status(500).Send(errInternalServer)
+		}
+
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-emotes.json"`, channel.Login))
+		return c.Status(200).Send(b)
+	})
+
+	//
+	// Import an emote export into a channel
+	//
+	// Query parameters:
+	// dry_run: only validate the import and preview its result
+	// replace: also remove the channel emotes which are not part of the import
+	//
+	router.Post("/:user/emotes/import", middleware.UserAuthMiddleware(true), func(c *fiber.Ctx) error {
+		c.Set("Content-Type", "application/json")
+		usr, ok := c.Locals("user").(*datastructure.User)
+		if !ok {
+			return c.Status(500).Send(errInternalServer)
+		}
+
+		dryRun := c.Query("dry_run") == "true"
+		replace := c.Query("replace") == "true"
+
+		var doc ChannelEmoteExport
+		if err := json.Unmarshal(c.Body(), &doc); err != nil {
+			return c.Status(400).Send(utils.S2B(fmt.Sprintf(errInvalidRequest, "Invalid JSON")))
+		}
+		if doc.Version < 1 || doc.Version > ChannelEmoteExportVersion {
+			return c.Status(400).Send(utils.S2B(fmt.Sprintf(errInvalidRequest, fmt.Sprintf("Unsupported Export Version (%d)", doc.Version))))
+		}
+
+		target, err := findUser(c.Context(), c.Params("user"))
+		if err != nil {
+			if err == mongo.ErrNoDocuments {
+				return c.Status(404).Send(utils.S2B(fmt.Sprintf(errNotFound, "Unknown User")))
+			}
+			log.Errorf("mongo, err=%v", err)
+			return c.Status(500).Send(errInternalServer)
+		}
+
+		channel, err := actions.GetChannel(c.Context(), target.ID)
+		if err != nil {
+			return c.Status(400).Send(utils.S2B(fmt.Sprintf(errInvalidRequest, err.Error())))
+		}
+		if err := authorization.Authorize(c.Context(), usr, authorization.ChannelEmotesSet, channel); err != nil {
+			if err == authorization.ErrInternal {
+				return c.Status(500).Send(errInternalServer)
+			}
+			return c.Status(403).Send(utils.S2B(fmt.Sprintf(errAccessDenied, err.Error())))
+		}
+
+		current := make(map[primitive.ObjectID]bool, len(channel.EmoteIDs))
+		for _, id := range channel.EmoteIDs {
+			current[id] = true
+		}
+
+		// Validate every entry of the import
+		valid := true
+		entries := []*channelEmoteImportEntryInfo{}
+		imported := make(map[primitive.ObjectID]bool, len(doc.Emotes))
+		emoteIDs := []primitive.ObjectID{}
+		for _, entry := range doc.Emotes {
+			info := &channelEmoteImportEntryInfo{
+				ID:   entry.ID,
+				Name: entry.Name,
+			}
+			entries = append(entries, info)
+
+			fail := func(reason string) {
+				valid = false
+				info.Action = "invalid"
+				info.Error = &reason
+			}
+
+			id, err := primitive.ObjectIDFromHex(entry.ID)
+			if err != nil {
+				fail("Invalid Emote ID")
+				continue
+			}
+			if imported[id] {
+				fail("Duplicate Entry")
+				continue
+			}
+			if entry.Alias != nil {
+				fail("Emote Aliases Are Not Supported")
+				continue
+			}
+			imported[id] = true
+
+			if current[id] {
+				info.Action = "unchanged"
+				continue
+			}
+
+			if _, err := actions.GetAddableEmote(c.Context(), channel, id); err != nil {
+				fail(err.Error())
+				continue
+			}
+
+			info.Action = "add"
+			emoteIDs = append(emoteIDs, id)
+		}
+
+		// Merge the imported emotes with the current ones
+		newIDs := []primitive.ObjectID{}
+		for _, id := range channel.EmoteIDs {
+			if replace && !imported[id] {
+				entries = append(entries, &channelEmoteImportEntryInfo{
+					ID:     id.Hex(),
+					Action: "remove",
+				})
+				continue
+			}
+
+			newIDs = append(newIDs, id)
+		}
+		newIDs = append(newIDs, emoteIDs...)
+
+		result := &channelEmoteImportResult{
+			Status:     200,
+			Message:    "success",
+			DryRun:     dryRun,
+			EmoteCount: len(newIDs),
+			Entries:    entries,
+		}
+		if !valid {
+			result.Message = "Some entries are invalid"
+		}
+		if err := actions.CheckChannelEmoteSlots(usr, channel, len(newIDs)); err != nil {
+			valid = false
+			result.Message = err.Error()
+		}
+		result.Valid = valid
+
+		if !valid && !dryRun {
+			result.Status = 400
+			b, err := json.Marshal(result)
+			if err != nil {
+				log.Errorf("json, err=%v", err)
+				return c.Status(500).Send(errInternalServer)
+			}
+			return c.Status(400).Send(b)
+		}
+
+		if !dryRun {
+			var reason *string
+			if r := c.Query("reason"); r != "" {
+				reason = &r
+			}
+			// Let the audit entry of the import pick up the request's metadata
+			ctx := context.WithValue(c.Context(), utils.RequestCtxKey, c)
+			if _, err := actions.SetChannelEmotes(ctx, usr, channel, newIDs, datastructure.AuditLogTypeUserChannelEmoteImport, reason); err != nil {
+				return c.Status(500).Send(errInternalServer)
+			}
+			result.Applied = true
+		}
+
+		b, err := json.Marshal(result)
+		if err != nil {
+			log.Errorf("json, err=%v", err)
+			return c.Status(500).Send(errInternalServer)
+		}
+
+		return c.Status(200).Send(b)
+	})
+}
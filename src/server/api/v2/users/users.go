@@ -0,0 +1,45 @@
+package users
+
+import (
+	"context"
+	"strings"
+
+	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
+	"github.com/gofiber/fiber/v2"
+	jsoniter "github.com/json-iterator/go"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var json = jsoniter.ConfigCompatibleWithStandardLibrary
+
+var (
+	errInternalServer = []byte(`{"status":500,"message":"internal server error"}`)
+	errInvalidRequest = `{"status":400,"message":"%s"}`
+	errAccessDenied   = `{"status":403,"message":"%s"}`
+	errNotFound       = `{"status":404,"message":"%s"}`
+)
+
+func Users(app fiber.Router) fiber.Router {
+	users := app.Group("/users")
+
+	ChannelEmotesRoute(users)
+
+	return users
+}
+
+// Find a user by their ID or login
+func findUser(ctx context.Context, idOrLogin string) (*datastructure.User, error) {
+	filter := bson.M{"login": strings.ToLower(idOrLogin)}
+	if id, err := primitive.ObjectIDFromHex(idOrLogin); err == nil {
+		filter = bson.M{"_id": id}
+	}
+
+	user := &datastructure.User{}
+	if err := mongo.Database.Collection("users").FindOne(ctx, filter).Decode(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -4,9 +4,11 @@ import (
 	"time"
 
 	"github.com/SevenTV/ServerGo/src/jwt"
+	api_audit "github.com/SevenTV/ServerGo/src/server/api/v2/audit"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/chatterino"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/emotes"
 	"github.com/SevenTV/ServerGo/src/server/api/v2/gql"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/users"
 	api_websocket "github.com/SevenTV/ServerGo/src/server/api/v2/websocket"
 	"github.com/SevenTV/ServerGo/src/server/middleware"
 	"github.com/gofiber/fiber/v2"
@@ -19,8 +21,10 @@ func API(app fiber.Router) fiber.Router {
 	api_websocket.WebSocket(api)
 	Twitch(api)
 	emotes.Emotes(api)
+	users.Users(api)
 	gql.GQL(api)
 	chatterino.Chatterino(api)
+	api_audit.Audit(api)
 
 	// Debug
 	app.Get("/debuguser/:user/:twid", func(c *fiber.Ctx) error {
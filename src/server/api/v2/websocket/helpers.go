@@ -4,10 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
 
 	"github.com/SevenTV/ServerGo/src/cache"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
 	"github.com/SevenTV/ServerGo/src/redis"
+	"github.com/SevenTV/ServerGo/src/server/api/v2/actions"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 	"go.mongodb.org/mongo-driver/bson"
@@ -19,6 +21,9 @@ type WebSocketHelpers struct {
 
 var subscriberChannelsUserEmotes = make(map[string]chan emoteSubscriptionResult)
 var subscriberCallersUserEmotes = make(map[string]map[uuid.UUID]func(emoteSubscriptionResult))
+var subscriberGlobalEmotesOnce sync.Once
+var subscriberCallersGlobalEmotes = make(map[uuid.UUID]func(emoteSubscriptionResult))
+var subscriberCallersGlobalEmotesMtx sync.RWMutex
 
 /*
 * SUBSCRIBER CHANNEL: User Emotes
@@ -94,3 +99,77 @@ func (h *WebSocketHelpers) SubscriberChannelUserEmotes(ctx context.Context, user
 	<-ctx.Done()
 	delete(subscriberCallersUserEmotes[userID], c.Stat.UUID)
 }
+
+/*
+* SUBSCRIBER CHANNEL: Global Emotes
+*
+* It listens for emotes becoming or ceasing to be global and forwards it to all active callers
+ */
+func (h *WebSocketHelpers) SubscriberChannelGlobalEmotes(ctx context.Context, cb func(emoteSubscriptionResult)) {
+	c := ctx.Value(WebSocketConnKey).(*Conn)
+	subscriberCallersGlobalEmotesMtx.Lock()
+	subscriberCallersGlobalEmotes[c.Stat.UUID] = cb
+	subscriberCallersGlobalEmotesMtx.Unlock()
+
+	// A single subscription is shared by every caller on this pod, for as long as it runs
+	subscriberGlobalEmotesOnce.Do(subscribeGlobalEmotes)
+
+	<-ctx.Done()
+	subscriberCallersGlobalEmotesMtx.Lock()
+	delete(subscriberCallersGlobalEmotes, c.Stat.UUID)
+	subscriberCallersGlobalEmotesMtx.Unlock()
+}
+
+// Subscribe to emotes becoming or ceasing to be global with Redis, and forward them to the active callers
+func subscribeGlobalEmotes() {
+	ctx := context.Background()
+
+	rCh := make(chan []byte)
+	redis.Subscribe(ctx, rCh, actions.GlobalEmotesChannel)
+
+	go func() {
+		for b := range rCh {
+			var d redis.PubSubPayloadGlobalEmotes
+			if err := json.Unmarshal(b, &d); err != nil {
+				log.Errorf("websocket, err=%v", err)
+				continue
+			}
+
+			var emote *datastructure.Emote
+			id, err := primitive.ObjectIDFromHex(d.ID)
+			if err != nil {
+				continue
+			}
+
+			if err := cache.FindOne(ctx, "emotes", "", bson.M{"_id": id}, &emote); err != nil {
+				continue
+			}
+			emote.URLs = datastructure.GetEmoteURLs(*emote)
+			emote.Provider = "7TV"
+
+			result := emoteSubscriptionResult{
+				Emote: &datastructure.Emote{
+					ID:         emote.ID,
+					Provider:   emote.Provider,
+					Visibility: emote.Visibility,
+					Mime:       emote.Mime,
+					Name:       emote.Name,
+					URLs:       emote.URLs,
+				},
+				Removed: d.Removed,
+			}
+
+			// Callers are copied, so that they can come and go while the result is forwarded
+			subscriberCallersGlobalEmotesMtx.RLock()
+			callers := make([]func(emoteSubscriptionResult), 0, len(subscriberCallersGlobalEmotes))
+			for _, fn := range subscriberCallersGlobalEmotes {
+				callers = append(callers, fn)
+			}
+			subscriberCallersGlobalEmotesMtx.RUnlock()
+
+			for _, fn := range callers {
+				fn(result)
+			}
+		}
+	}()
+}
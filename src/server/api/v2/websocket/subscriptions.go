@@ -14,8 +14,15 @@ func createChannelEmoteSubscription(ctx context.Context, c *Conn, channel string
 	})
 }
 
+func createGlobalEmoteSubscription(ctx context.Context, c *Conn) {
+	// Subscribe to these events with Redis
+	c.helpers.SubscriberChannelGlobalEmotes(ctx, func(res emoteSubscriptionResult) {
+		c.SendOpDispatch(ctx, res, "GLOBAL_EMOTES_UPDATE")
+	})
+}
+
 type emoteSubscriptionResult struct {
 	Emote   *datastructure.Emote `json:"emote"`
 	Removed bool                 `json:"removed"`
-	Actor   string               `json:"actor"`
+	Actor   string               `json:"actor,omitempty"`
 }
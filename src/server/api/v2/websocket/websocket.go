@@ -121,6 +121,9 @@ func WebSocket(app fiber.Router) {
 						channel := data.Params["channel"]
 						go createChannelEmoteSubscription(ctx, c, channel)
 
+					case WebSocketSubscriptionGlobalEmotes: // Subscribe: GLOBAL EMOTES
+						go createGlobalEmoteSubscription(ctx, c)
+
 					default: // Unknown Subscription
 						c.SendClosure(1003, "Unknown Subscription Type")
 					}
@@ -297,6 +300,7 @@ const (
 
 const (
 	WebSocketSubscriptionChannelEmotes int8 = 1 + iota
+	WebSocketSubscriptionGlobalEmotes
 )
 
 const WebSocketConnKey = utils.Key("conn")
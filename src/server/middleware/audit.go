@@ -1,7 +1,7 @@
 package middleware
 
 import (
-	"github.com/SevenTV/ServerGo/src/mongo"
+	"github.com/SevenTV/ServerGo/src/audit"
 	"github.com/SevenTV/ServerGo/src/mongo/datastructure"
 	"github.com/gofiber/fiber/v2"
 	log "github.com/sirupsen/logrus"
@@ -14,7 +14,8 @@ func AuditRoute(r AuditedRoute) func(*fiber.Ctx) error {
 		statusCode, body, auditEntry := r(c)
 
 		if auditEntry != nil {
-			_, err := mongo.Database.Collection("audit").InsertOne(c.Context(), auditEntry)
+			auditEntry.Request = audit.RequestFromFiber(c)
+			err := audit.Insert(c.Context(), auditEntry)
 			if err != nil {
 				log.Errorf("audit, err=%v", err)
 			}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"strconv"
 	"strings"
 	"time"
 
@@ -16,11 +17,12 @@ import (
 )
 
 type PayloadJWT struct {
-	ID           primitive.ObjectID `json:"id"`          // User App ID
-	TWID         string             `json:"twid"`        // Twitch ID
-	Permissions  string             `json:"permissions"` // Permission bitmask from user's role
-	TokenVersion string             `json:"version"`     // Token version to match against for JWT invalidation
-	CreatedAt    time.Time          `json:"created_at"`
+	ID           primitive.ObjectID  `json:"id"`                 // User App ID
+	TWID         string              `json:"twid"`               // Twitch ID
+	Permissions  string              `json:"permissions"`        // Permission bitmask requests are limited to, set on personal access tokens
+	TokenVersion string              `json:"version"`            // Token version to match against for JWT invalidation
+	TokenID      *primitive.ObjectID `json:"token_id,omitempty"` // ID of the personal access token, if this is one
+	CreatedAt    time.Time           `json:"created_at"`
 }
 
 func UserAuthMiddleware(required bool) func(c *fiber.Ctx) error {
@@ -60,7 +62,8 @@ func UserAuthMiddleware(required bool) func(c *fiber.Ctx) error {
 			})
 		}
 
-		if pl.CreatedAt.Before(time.Now().Add(-time.Hour * 24 * 60)) {
+		// Personal access tokens have their own expiry, checked once the token is found
+		if pl.TokenID == nil && pl.CreatedAt.Before(time.Now().Add(-time.Hour*24*60)) {
 			if !required {
 				return c.Next()
 			}
@@ -143,6 +146,68 @@ func UserAuthMiddleware(required bool) func(c *fiber.Ctx) error {
 			})
 		}
 
+		var accessToken *datastructure.AccessToken
+		if pl.TokenID != nil {
+			permissions, err := strconv.ParseInt(pl.Permissions, 10, 64)
+			if err != nil {
+				if !required {
+					return c.Next()
+				}
+				return c.Status(403).JSON(&fiber.Map{
+					"status": 403,
+					"error":  "Invalid Token",
+				})
+			}
+
+			accessToken = &datastructure.AccessToken{}
+			err = mongo.Database.Collection("access_tokens").FindOne(c.Context(), bson.M{
+				"_id":         pl.TokenID,
+				"user_id":     user.ID,
+				"permissions": permissions,
+			}).Decode(accessToken)
+			if err != nil {
+				if err == mongo.ErrNoDocuments {
+					if !required {
+						return c.Next()
+					}
+					return c.Status(403).JSON(&fiber.Map{
+						"status": 403,
+						"error":  "Invalid Token",
+					})
+				}
+				log.Errorf("mongo, err=%v", err)
+				if !required {
+					return c.Next()
+				}
+				return c.Status(500).JSON(&fiber.Map{
+					"status": 500,
+					"error":  "Internal Server Error",
+				})
+			}
+
+			// Expired tokens may linger until mongo removes them
+			if accessToken.ExpireAt.Before(time.Now()) {
+				if !required {
+					return c.Next()
+				}
+				return c.Status(403).JSON(&fiber.Map{
+					"status": 403,
+					"error":  "Access Token Expired",
+				})
+			}
+
+			// The last use is only recorded once a minute, to spare a write on every request of busy bots
+			if accessToken.LastUsedAt == nil || accessToken.LastUsedAt.Before(time.Now().Add(-time.Minute)) {
+				now := time.Now()
+				if _, err := mongo.Database.Collection("access_tokens").UpdateOne(c.Context(), bson.M{"_id": accessToken.ID}, bson.M{
+					"$set": bson.M{"last_used_at": now},
+				}); err != nil {
+					log.Errorf("mongo, err=%v", err)
+				}
+				accessToken.LastUsedAt = &now
+			}
+		}
+
 		// Assign role to user
 		if user.RoleID != nil {
 			role := datastructure.GetRole(c.Context(), user.RoleID)                                             // Try to get the cached role
@@ -151,6 +216,9 @@ func UserAuthMiddleware(required bool) func(c *fiber.Ctx) error {
 			user.Role = datastructure.DefaultRole // If no role assign default role
 		}
 
+		// Requests made with an access token are limited to its permissions and scopes
+		user.AccessToken = accessToken
+
 		c.Locals("user", user)
 
 		return c.Next()
@@ -17,6 +17,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
 )
 
 type Server struct {
@@ -59,6 +60,9 @@ func New() *Server {
 
 	server.app.Use(recover.New())
 
+	// Give every request an id, which is attached to the audit entries it creates
+	server.app.Use(requestid.New())
+
 	server.app.Use(func(c *fiber.Ctx) error {
 		c.Set("X-Node-Name", configure.NodeName)
 		c.Set("X-Pod-Name", configure.PodName)